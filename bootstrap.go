@@ -1,9 +1,16 @@
 package gocli
 
 import (
+	stdctx "context"
+	"errors"
+	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -12,41 +19,136 @@ const (
 )
 
 var (
-	LogFlag   = NewFlag("logf", "-logf ./app.log 指定日志文件")
-	UiFlag    = NewFlag("ui", "程序启用GUI,输入指令会忽略")
-	PluginDir = NewFlag("pdir", "-pdir {dir} 添加插件目录,可多个")
-	LogFLevel = NewFlag("logl", "-logl {0-5} 日志输出级别(0-5)对应 debug-error")
-	WorkDir   = NewFlag("wdir", "-wdir 指定工作目录")
-	CheckSum  = NewFlag("check", "-check") //验证插件签名
+	LogFlag         = NewFlag("logf", "-logf ./app.log 指定日志文件")
+	UiFlag          = BoolFlag("ui", "程序启用GUI,输入指令会忽略")
+	ReplFlag        = BoolFlag("repl", "-repl 行模式交互,不使用全屏界面,Ctrl-D退出")
+	PluginDir       = StringsFlag("pdir", "-pdir {dir} 添加插件目录,可多个")
+	LogFLevel       = IntFlag("logl", "-logl {0-5} 日志输出级别(0-5)对应 debug-error").Default(LOG_INFO)
+	WorkDir         = PathFlag("wdir", "-wdir 指定工作目录").Default(".")
+	CheckSum        = BoolFlag("check", "-check 只加载已签名且公钥可信的插件") //验证插件签名
+	TrustDir        = PathFlag("trust", "-trust {dir} 插件签名的可信公钥目录(*.pub),默认~/.gocli/trust").Env("GOCLI_TRUST")
+	StrictArg       = BoolFlag("strict", "-strict 拒绝指令未声明的flag")
+	Script          = PathFlag("script", "-script {file} 逐行执行脚本文件中的指令")
+	ContinueOnErr   = BoolFlag("continue-on-error", "-continue-on-error 脚本中指令出错时继续执行")
+	OutputFormat    = EnumFlag([]string{OutputText, OutputJson, OutputYaml, OutputTable}, "o", "-o 结果输出格式", "output").Default(OutputText)
+	Frame           = EnumFlag([]string{FrameText, FrameJson}, "frame", "-frame 从标准输入读取指令时,结果的输出格式").Default(FrameText)
+	Timeout         = DurationFlag("timeout", "-timeout 30s 每条指令的超时时间,超时后取消")
+	ShutdownTimeout = DurationFlag("shutdown-timeout", "-shutdown-timeout 5s 退出时关闭插件的时限").Default(5 * time.Second)
 )
 
+// 启动flags,所有指令均可使用
+var bootFlags = []Flag{LogFlag, UiFlag, ReplFlag, PluginDir, LogFLevel, WorkDir, CheckSum, StrictArg, Script, ContinueOnErr, Frame, OutputFormat, Timeout, ShutdownTimeout, TrustDir}
+
 func CLI() *BootStrap {
 	return &BootStrap{}
 }
 
 type BootStrap struct {
 	stop atomic.Bool
+	// 按安装顺序记录,退出时逆序关闭
+	installed []*RegisteredPlugin
 }
 
 func (boot *BootStrap) Run(args []string) Message {
 
-	arg, fmap := ParseInputArgs(args)
-	// run mode
-	_, ok := fmap.HasFlag(UiFlag)
-	context := boot.initContext(ok, arg, fmap)
+	// shell补全时,按待补全的输入加载插件,且不输出日志
+	bootArgs := args
+	words, completing := completeRequest(args)
+	if completing {
+		bootArgs = words
+	}
+	arg, fmap, perr := ParseArgs(bootFlags, bootArgs)
+	if perr == nil {
+		perr = ResolveFlags(bootFlags, fmap)
+	}
+	if perr != nil {
+		if !completing {
+			return perr
+		}
+		arg, fmap = nil, NewFMap(nil)
+	}
+	mode := runModeOf(completing, arg, fmap)
+	context := boot.initContext(mode != modeExec, arg, fmap)
+	if mode == modeComplete {
+		context.SetValue(output_sink, DiscardSink)
+	}
+	// 收到中断信号时取消执行中的指令,全屏界面随之退出;之后关闭插件。
+	// 行模式自行处理中断信号,只取消执行中的指令
+	if mode != modeComplete && mode != modeRepl {
+		std, stop := signalContext(stdctx.Background())
+		defer stop()
+		context.std = std
+	}
+	timeout, _ := FlagValue(fmap, ShutdownTimeout)
+	defer boot.shutdown(context, timeout)
 
-	values, _ := fmap.HasFlag(PluginDir)
+	values, _ := FlagValue(fmap, PluginDir)
 	registrey := context.registry()
-	_, verify := fmap.HasFlag(CheckSum)
-	boot.registerPlugin(context, verify, values)
+	verify, _ := FlagValue(fmap, CheckSum)
+	trust := boot.trustStore(context, fmap)
+	if err := boot.registerPlugin(context, verify, trust, values); err != nil && mode != modeComplete {
+		return WrapMessage(CodePlugin, err, "插件初始化失败")
+	}
 	registrey.Finish(true)
-	if ok {
+	return boot.run(context, mode, args, fmap)
+}
+
+func (boot *BootStrap) run(context registreyContext, mode runMode, args Args, fmap FlagMap) Message {
+	switch mode {
+	case modeScript:
+		script, _ := FlagValue(fmap, Script)
+		cont, _ := FlagValue(fmap, ContinueOnErr)
+		if abs, err := filepath.Abs(script); err == nil {
+			script = abs
+		}
+		return RunScriptFile(context, script, cont)
+	case modeStdin:
+		frame, _ := FlagValue(fmap, Frame)
+		return RunStream(context, os.Stdin, os.Stdout, frame)
+	case modeRepl:
+		return NewRepl().Run("> ", context)
+	case modeUi:
 		return NewUi().Run("> ", context)
 	}
-	return boot.exec(context, arg, fmap)
+	return boot.exec(context, args)
 }
 
-func (boot *BootStrap) initContext(ui bool, args []string, fmap FlagMap) registreyContext {
+type runMode = int
+
+const (
+	modeExec runMode = iota
+	modeComplete
+	modeScript
+	modeStdin
+	modeRepl
+	modeUi
+)
+
+// 没有输入指令时:标准输入非终端则逐行读取指令,否则进入交互界面
+func runModeOf(completing bool, args Args, fmap FlagMap) runMode {
+	if completing {
+		return modeComplete
+	}
+	if script, _ := FlagValue(fmap, Script); len(script) > 0 {
+		return modeScript
+	}
+	if repl, _ := FlagValue(fmap, ReplFlag); repl {
+		return modeRepl
+	}
+	if ui, _ := FlagValue(fmap, UiFlag); ui {
+		return modeUi
+	}
+	if len(args) == 0 {
+		if stdinPiped() {
+			return modeStdin
+		}
+		return modeUi
+	}
+	return modeExec
+}
+
+// quiet为true时日志不输出到终端
+func (boot *BootStrap) initContext(quiet bool, args []string, fmap FlagMap) *context {
 	if len(args) == 0 {
 		fmap.Set(UiFlag.Name())
 		pdir := "./plugins"
@@ -73,24 +175,20 @@ func (boot *BootStrap) initContext(ui bool, args []string, fmap FlagMap) registr
 		return time.Now().Format("2006-01-02 15:04:05.999")
 	})
 	var console Console
-	if ui {
+	if quiet {
 		console = NewConsole(nil, w)
 	} else {
 		console = NewConsole(os.Stdout, w)
 	}
 	registry := NewRegistry()
+	strict, _ := FlagValue(fmap, StrictArg)
+	registry.Strict(strict)
 	log, _ := console.Log()
 	registry.Logger(log)
 	log.Info("设置logger,boot,registry")
-	v, ok := fmap.GetInt(LogFLevel.Name())
-	if !ok {
-		v = LOG_INFO
-	}
+	v, _ := FlagValue(fmap, LogFLevel)
 	console.Level(v)
-	wdir, ok := fmap.GetString(WorkDir.Name())
-	if !ok {
-		wdir = "."
-	}
+	wdir, _ := FlagValue(fmap, WorkDir)
 	ctx := &context{
 		console:   console,
 		registrey: registry,
@@ -103,61 +201,192 @@ func (boot *BootStrap) initContext(ui bool, args []string, fmap FlagMap) registr
 	return ctx
 }
 
-func (boot *BootStrap) registerPlugin(ctx registreyContext, verify bool, pluginDir []string) error {
+// 读取可信公钥,供插件签名校验及 plugin keys 使用
+func (boot *BootStrap) trustStore(ctx registreyContext, fmap FlagMap) *TrustStore {
+	dir, _ := FlagValue(fmap, TrustDir)
+	if len(dir) == 0 {
+		dir = DefaultTrustDir()
+	}
+	store, err := LoadTrustStore(dir)
+	if err != nil {
+		logger, _ := ctx.Logger()
+		logger.Warn("[trust] %s", err.Error())
+	}
+	ctx.SetValue(trust_store, store)
+	return store
+}
+
+func (boot *BootStrap) registerPlugin(ctx registreyContext, verify bool, trust *TrustStore, pluginDir []string) error {
 	boot.internalPluginRegister(ctx)
 	logger, _ := ctx.Logger()
 	register := ctx.registry()
 	if len(pluginDir) > 0 {
-		plugins, num := LoadPlugin(pluginDir, logger)
-		logger.Info("found %d plugins", num)
-
-		for _, lp := range plugins {
-			if !verify || lp.Verified {
-				logger.Info("install plugin %s,md5:%s,file:%s", lp.Name(), lp.Digest, lp.File)
-				register.RegisterPlugins(lp)
-			} else {
-				logger.Err("Plugin name:%s,path:%s ;checksum failed", lp.Plugin.Name(), lp.File)
+		report := LoadPlugin(pluginDir, trust, verify, logger)
+		ctx.SetValue(load_report, report)
+		logger.Info("found %d plugins,loaded %d", report.Found, len(report.Loaded))
+		for _, r := range report.Rejected {
+			logger.Err("[load plugin] rejected %s", r.Error())
+		}
+		for _, r := range report.Warnings {
+			logger.Warn("[load plugin] %s,未使用-check,仍然加载", r.Error())
+		}
+		for _, lp := range report.Loaded {
+			logger.Info("install plugin %s,sha256:%s,signer:%s,file:%s", lp.Name(), lp.Digest, lp.Signer, lp.File)
+			if err := register.RegisterPlugins(lp); err != nil {
+				logger.Err("[load plugin] %s", err.Error())
 			}
 		}
-
 	}
-	var err error
-	cmap := make(map[string]PluginContext, 7)
-	register.RangePlugin(func(key string, plugins map[string]*RegisteredPlugin) (next bool) {
-		p := plugins[key]
+	// 被依赖的插件先Setup及BeforeRun
+	plugins, err := register.InstallOrder()
+	if err != nil {
+		return err
+	}
+	cmap := make(map[string]PluginContext, len(plugins))
+	for _, p := range plugins {
 		ctx := NewPluginContext(ctx, p.Plugin)
-		if e := p.Setup(ctx); err != nil {
-			err = e
-			return false
+		if e := p.Setup(ctx); e != nil {
+			return fmt.Errorf("%s setup: %w", p.Name(), e)
 		}
-		cmap[key] = ctx
-		return true
-	})
-	if err == nil {
-		register.RangePlugin(func(key string, plugins map[string]*RegisteredPlugin) (next bool) {
-			p := plugins[key]
-			if e := p.BeforeRun(cmap[key]); e != nil {
-				err = e
-				return false
+		cmap[p.Name()] = ctx
+	}
+	for _, p := range plugins {
+		if e := p.BeforeRun(cmap[p.Name()]); e != nil {
+			return fmt.Errorf("%s before run: %w", p.Name(), e)
+		}
+		p.Installed()
+		boot.installed = append(boot.installed, p)
+	}
+	return nil
+}
+
+// 按安装的逆序调用插件的Shutdown,超过timeout后不再等待,余下的插件跳过
+func (boot *BootStrap) shutdown(ctx registreyContext, timeout time.Duration) []error {
+	std, cancel := stdctx.WithTimeout(stdctx.Background(), timeout)
+	defer cancel()
+	sctx := &cancelContext{registreyContext: ctx, std: std}
+	console := ctx.StdConsole()
+	errs := make([]error, 0)
+	installed := boot.installed
+	boot.installed = nil
+shutdown:
+	for i := len(installed) - 1; i >= 0; i-- {
+		p := installed[i]
+		sp, ok := shutdownOf(p.Plugin)
+		if !ok {
+			continue
+		}
+		done := make(chan error, 1)
+		go func() {
+			done <- sp.Shutdown(NewPluginContext(sctx, p.Plugin))
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s shutdown: %w", p.Name(), err))
 			}
-			p.Installed()
-			return true
-		})
+		case <-std.Done():
+			errs = append(errs, fmt.Errorf("%s shutdown: %w,余下%d个插件未关闭", p.Name(), std.Err(), i))
+			break shutdown
+		}
 	}
-	return err
+	for _, err := range errs {
+		console.Warn("%s", err.Error())
+	}
+	return errs
 }
 
 func (boot *BootStrap) internalPluginRegister(context registreyContext) {
 	context.registry().RegisterPlugins(gogenCore)
 }
 
-func (boot *BootStrap) exec(ctx registreyContext, args []string, fmap FlagMap) Message {
-	if len(args) == 0 {
-		return WarnMessage(0, "no command found")
+func (boot *BootStrap) exec(ctx registreyContext, tokens Args) Message {
+	c, arg, fmap, msg := resolveInput(ctx.registry(), tokens)
+	if msg != nil {
+		return msg
+	}
+	return runCommand(ctx, c, arg, fmap)
+}
+
+var exitSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// 收到中断信号时取消,取消后恢复默认处理,再次中断即退出进程
+func signalContext(parent stdctx.Context) (stdctx.Context, func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, exitSignals...)
+	return watchSignals(parent, ch, func() { signal.Stop(ch) })
+}
+
+// 从signals收到信号或stop时取消,并调用一次release
+func watchSignals(parent stdctx.Context, signals <-chan os.Signal, release func()) (stdctx.Context, func()) {
+	std, cancel := stdctx.WithCancel(parent)
+	var once sync.Once
+	stop := func() {
+		once.Do(release)
+		cancel()
 	}
-	c, arg, ok := matchCommand(ctx.registry(), args)
-	if !ok || c.Command == nil {
-		return WarnMessage(404, "command not found")
+	go func() {
+		select {
+		case <-signals:
+		case <-std.Done():
+		}
+		stop()
+	}()
+	return std, stop
+}
+
+// 执行指令,-timeout 限制执行时间,结构化结果按 -o 指定的格式输出
+func runCommand(ctx Context, c RegisteredCommand, args Args, fmap FlagMap) Message {
+	timeout, _ := FlagValue(fmap, Timeout)
+	if timeout > 0 {
+		var cancel func()
+		ctx, cancel = WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	msg := c.Run(NewPContext(ctx, c.From), args, fmap)
+	// 超时或取消后失败的指令,以取消原因作为错误原因
+	if msg != nil && ctx.Err() != nil {
+		if _, failed := msg.Err(); failed {
+			rm := Rich(msg)
+			if rm.Unwrap() == nil {
+				rm.Wrap(ctx.Err())
+			}
+			if errors.Is(ctx.Err(), stdctx.DeadlineExceeded) {
+				rm.WithHint("指令超时(-timeout %s)", timeout)
+			}
+			msg = rm
+		}
+	}
+	// 严格模式下未声明的flag已被拒绝,否则提示与输入相近的flag
+	msg = withFlagHint(msg, flagSuggestions(append(c.Flags(), bootFlags...), fmap))
+	format, _ := FlagValue(fmap, OutputFormat)
+	return RenderMessage(msg, format)
+}
+
+// 匹配指令,并按指令声明的flags解析输入
+func resolveInput(r Registry, tokens Args) (c RegisteredCommand, args Args, fmap FlagMap, msg Message) {
+	positional, _, msg := ParseArgs(bootFlags, tokens)
+	if msg != nil {
+		return
+	}
+	c, cargs, msg := resolveCommand(r, positional)
+	if msg != nil {
+		return
+	}
+	args, fmap, msg = commandInput(c.Command, tokens, len(positional)-len(cargs), bootFlags...)
+	if msg != nil {
+		return c, args, fmap, helpRef(msg, c.Command.Key())
+	}
+	if sc, ok := commandAs[StrictCommand](c.Command); r.IsStrict() || (ok && sc.Strict()) {
+		msg = strictFlagsCheck(append(c.Flags(), bootFlags...), fmap)
+	}
+	return c, args, fmap, helpRef(msg, c.Command.Key())
+}
+
+// 用法及校验错误附带指令的帮助引用
+func helpRef(msg Message, key string) Message {
+	if msg == nil || (msg.Code() != CodeUsage && msg.Code() != CodeInvalid) {
+		return msg
 	}
-	return c.Run(NewPContext(ctx, c.From), arg, fmap)
+	return Rich(msg).WithHelp(key)
 }
@@ -1,7 +1,12 @@
 package gocli
 
 import (
+	stdctx "context"
+	"errors"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestBootRun(t *testing.T) {
@@ -9,3 +14,59 @@ func TestBootRun(t *testing.T) {
 	msg := boot.Run([]string{"command", "help", "command", "-logf", "/Logs/gogen_new.log"})
 	println(msg.Msg())
 }
+
+func TestShutdown(t *testing.T) {
+	ctx := testContext(t)
+	var (
+		mux     sync.Mutex
+		stopped []string
+	)
+	cause := errors.New("flush failed")
+	plugin := func(name string, err error) Plugin {
+		return &GeneralPlugin{ID: name, Stop: func(ctx Context) error {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Errorf("%s: shutdown context should have a deadline", name)
+			}
+			mux.Lock()
+			defer mux.Unlock()
+			stopped = append(stopped, name)
+			return err
+		}}
+	}
+	ctx.registry().RegisterPlugins(plugin("db_plugin", cause), plugin("cache_plugin", nil), plugin("log_plugin", nil))
+	boot := CLI()
+	if err := boot.registerPlugin(ctx, false, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	expect := make([]string, 0, 3)
+	for i := len(boot.installed) - 1; i >= 0; i-- {
+		if name := boot.installed[i].Name(); name != gogenCore.Name() {
+			expect = append(expect, name)
+		}
+	}
+	errs := boot.shutdown(ctx, time.Second)
+	if !reflect.DeepEqual(stopped, expect) {
+		t.Errorf("expect reverse install order %v, got %v", expect, stopped)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], cause) {
+		t.Errorf("expect db_plugin error, got %v", errs)
+	}
+	if errs := boot.shutdown(ctx, time.Second); len(errs) > 0 {
+		t.Errorf("plugins should only be shut down once, got %v", errs)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	ctx := testContext(t)
+	ctx.registry().RegisterPlugins(&GeneralPlugin{ID: "slow_plugin", Stop: func(ctx Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}})
+	boot := CLI()
+	boot.registerPlugin(ctx, false, nil, nil)
+	start := time.Now()
+	errs := boot.shutdown(ctx, 20*time.Millisecond)
+	if len(errs) != 1 || !errors.Is(errs[0], stdctx.DeadlineExceeded) || time.Since(start) > time.Second/2 {
+		t.Errorf("expect deadline exceeded, got %v", errs)
+	}
+}
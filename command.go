@@ -1,7 +1,5 @@
 package gocli
 
-import "strings"
-
 type Args = []string
 
 type ExecFunc = func(ctx Context, args []string, flagmap FlagMap) Message
@@ -17,6 +15,99 @@ type Command interface {
 	Flags() []Flag
 }
 
+// 指令别名,每个别名为完整的指令key,如 "history" 对应 "show history"
+type AliasCommand interface {
+	Command
+	Aliases() []string
+}
+
+func WithAliases(cmd Command, aliases ...string) Command {
+	return &aliasCommand{Command: cmd, aliases: aliases}
+}
+
+type aliasCommand struct {
+	Command
+	aliases []string
+}
+
+func (c *aliasCommand) Aliases() []string {
+	return c.aliases
+}
+
+func (c *aliasCommand) unwrap() Command {
+	return c.Command
+}
+
+// 严格模式:输入未声明的flag时报错
+type StrictCommand interface {
+	Command
+	Strict() bool
+}
+
+func WithStrict(cmd Command) Command {
+	return &strictCommand{Command: cmd}
+}
+
+type strictCommand struct {
+	Command
+}
+
+func (c *strictCommand) Strict() bool {
+	return true
+}
+
+func (c *strictCommand) unwrap() Command {
+	return c.Command
+}
+
+// 隐藏指令:可执行,但不出现在帮助信息,提示及补全中
+type HiddenCommand interface {
+	Command
+	Hidden() bool
+}
+
+func WithHidden(cmd Command) Command {
+	return &hiddenCommand{Command: cmd}
+}
+
+type hiddenCommand struct {
+	Command
+}
+
+func (c *hiddenCommand) Hidden() bool {
+	return true
+}
+
+func (c *hiddenCommand) unwrap() Command {
+	return c.Command
+}
+
+func isHidden(c Command) bool {
+	hc, ok := commandAs[HiddenCommand](c)
+	return ok && hc.Hidden()
+}
+
+// WithAliases,WithStrict 等包装的指令
+type commandWrapper interface {
+	unwrap() Command
+}
+
+// 沿包装链查找实现了T的指令
+func commandAs[T any](c Command) (T, bool) {
+	for c != nil {
+		if t, ok := c.(T); ok {
+			return t, true
+		}
+		w, ok := c.(commandWrapper)
+		if !ok {
+			break
+		}
+		c = w.unwrap()
+	}
+	var t T
+	return t, false
+}
+
 func NewRootCommand(key string, usage string) Command {
 	return NewCommand(key, usage, NothingDo)
 }
@@ -65,20 +156,53 @@ func MergeFlagMap(args []string, fmap FlagMap) Args {
 	return append(args, fmap.toArgs()...)
 }
 
-func ParseLine(input string) (Args, FlagMap) {
-	return ParseInputArgs(strings.Fields(input))
+func ParseLine(input string) (Args, FlagMap, Message) {
+	tokens, msg := Tokenize(input)
+	if msg != nil {
+		return nil, nil, msg
+	}
+	args, fmap := ParseInputArgs(tokens)
+	return args, fmap, nil
 }
 
 func ParseInputArgs(args []string) (Args, FlagMap) {
-	endPos := -1
-	for i := range args {
-		if strings.HasPrefix(args[i], "-") {
-			endPos = i
-			break
+	positional, fmap, _ := ParseArgs(nil, args)
+	return positional, fmap
+}
+
+// 指令的flags加上未被同名覆盖的全局flags
+func withGlobals(own []Flag, globals []Flag) []Flag {
+	flags := make([]Flag, 0, len(own)+len(globals))
+	flags = append(flags, own...)
+	declared := make(map[string]bool, len(own)*2)
+	for _, name := range flagNames(own) {
+		declared[name] = true
+	}
+	for i := range globals {
+		shadowed := declared[globals[i].Name()]
+		if alias, ok := globals[i].Alias(); ok && declared[alias] {
+			shadowed = true
+		}
+		if !shadowed {
+			flags = append(flags, globals[i])
 		}
 	}
-	if endPos > -1 {
-		return args[0:endPos], NewFMap(args[endPos:])
+	return flags
+}
+
+// 按指令声明的flags解析输入,skip为已匹配的指令key个数
+func commandInput(c Command, tokens Args, skip int, globals ...Flag) (Args, FlagMap, Message) {
+	var own []Flag
+	if c != nil {
+		own = c.Flags()
+	}
+	flags := withGlobals(own, globals)
+	args, fmap, msg := ParseArgs(flags, tokens)
+	if msg == nil {
+		msg = ResolveFlags(flags, fmap)
+	}
+	if skip > len(args) {
+		skip = len(args)
 	}
-	return args, NewFMap(make([]string, 0))
+	return args[skip:], fmap, msg
 }
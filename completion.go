@@ -0,0 +1,243 @@
+package gocli
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 补全当前输入的参数值,args为已输入的指令参数,current为正在输入的词
+type CompleteFunc = func(ctx Context, args Args, current string) []string
+
+// 支持动态补全参数值的指令
+type Completer interface {
+	Command
+	Complete(ctx Context, args Args, current string) []string
+}
+
+func WithCompleter(cmd Command, complete CompleteFunc) Command {
+	return &completeCommand{Command: cmd, complete: complete}
+}
+
+type completeCommand struct {
+	Command
+	complete CompleteFunc
+}
+
+func (c *completeCommand) Complete(ctx Context, args Args, current string) []string {
+	return c.complete(ctx, args, current)
+}
+
+func (c *completeCommand) unwrap() Command {
+	return c.Command
+}
+
+// 文件路径补全,目录以/结尾
+func CompletePath(ctx Context, args Args, current string) []string {
+	dir, prefix := filepath.Split(current)
+	search := dir
+	if len(search) == 0 {
+		search = "."
+	}
+	if !filepath.IsAbs(search) && ctx != nil && len(ctx.WorkDir()) > 0 {
+		search = filepath.Join(ctx.WorkDir(), search)
+	}
+	files, err := os.ReadDir(search)
+	if err != nil {
+		return nil
+	}
+	entries := make([]string, 0, len(files))
+	for _, f := range files {
+		name := f.Name()
+		if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		entry := dir + name
+		if f.IsDir() {
+			entry += "/"
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// 已注册插件名称补全
+func CompletePlugins(ctx Context, args Args, current string) []string {
+	names := make([]string, 0, 5)
+	for name := range ctx.RegisteredPlugins() {
+		names = append(names, name)
+	}
+	return withPrefix(names, current)
+}
+
+// 根指令补全
+func CompleteRootCommands(ctx Context, args Args, current string) []string {
+	return withPrefix(rootKeys(registrey(ctx)), current)
+}
+
+func withPrefix(candidates []string, prefix string) []string {
+	found := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			found = append(found, c)
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+func rootKeys(r Registry) []string {
+	keys := make([]string, 0, 13)
+	r.RangeRootCommand(func(key string, roots map[string]*RegisteredCommand) (next bool) {
+		if !roots[key].IsHidden() {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	return keys
+}
+
+// 补全words之后正在输入的词current,返回候选词:
+// 根指令,子指令,未输入过的flag,flag可选值,以及Completer提供的参数值
+func completeWords(ctx Context, r Registry, words Args, current string) []string {
+	roots := make(map[string]*RegisteredCommand, 13)
+	r.RangeRootCommand(func(key string, all map[string]*RegisteredCommand) (next bool) {
+		roots[key] = all[key]
+		return true
+	})
+	positional, _, _ := ParseArgs(bootFlags, words)
+	if len(positional) == 0 {
+		if strings.HasPrefix(current, "-") {
+			return nil
+		}
+		candidates := rootKeys(r)
+		for _, key := range r.CommandKeys() {
+			if !strings.Contains(key, " ") {
+				candidates = append(candidates, key)
+			}
+		}
+		return withPrefix(dedup(candidates), current)
+	}
+	node, found := roots[positional[0]]
+	if !found {
+		if ac, ok := r.Alias(positional[0:1]); ok {
+			node, found = &ac, true
+		}
+	}
+	if !found {
+		return nil
+	}
+	var (
+		c     *RegisteredCommand
+		depth = 1
+	)
+	if node.Command != nil {
+		c = node
+	}
+	walked := true
+	for i := 1; i < len(positional); i++ {
+		sub, ok := node.SubCommands[positional[i]]
+		if !ok {
+			walked = false
+			break
+		}
+		node, depth = sub, i+1
+		if node.Command != nil {
+			c = node
+		}
+	}
+	var flags []Flag
+	if c != nil {
+		flags = c.Flags()
+	}
+	if strings.HasPrefix(current, "-") {
+		_, fmap, _ := ParseArgs(flags, words)
+		given := make(map[string]bool, 5)
+		for i := range flags {
+			if _, ok := fmap.HasFlag(flags[i]); ok {
+				given[flags[i].Name()] = true
+			}
+		}
+		candidates := make([]string, 0, len(flags))
+		for i := range flags {
+			if given[flags[i].Name()] {
+				continue
+			}
+			candidates = append(candidates, flagNames(flags[i:i+1])...)
+		}
+		return withPrefix(candidates, current)
+	}
+	// 上一个词是需要值的flag
+	last := words[len(words)-1]
+	spec := newFlagSpec(flags)
+	if f, ok := spec.lookup(last); ok && arityOf(f) != 0 {
+		if opts, ok := f.(FlagOptions); ok && len(opts.Options()) > 0 {
+			return withPrefix(opts.Options(), current)
+		}
+		if def, ok := f.(FlagDefinition); ok && def.TypeName() == "path" {
+			return CompletePath(ctx, nil, current)
+		}
+	}
+	candidates := make([]string, 0, 7)
+	if walked && depth == len(positional) && len(positional) == len(words) {
+		for key, sub := range node.SubCommands {
+			if !sub.IsHidden() {
+				candidates = append(candidates, key)
+			}
+		}
+	}
+	if c != nil {
+		if cc, ok := commandAs[Completer](c.Command); ok {
+			cargs, _, _ := ParseArgs(append(flags, bootFlags...), words)
+			if depth <= len(cargs) {
+				cargs = cargs[depth:]
+			}
+			pctx := ctx
+			if rctx, ok := ctx.(registreyContext); ok {
+				pctx = NewPContext(rctx, c.From)
+			}
+			candidates = append(candidates, cc.Complete(pctx, cargs, current)...)
+		}
+	}
+	return withPrefix(dedup(candidates), current)
+}
+
+// 按输入行补全,返回补全后的完整输入行
+func completeLine(ctx Context, r Registry, line string) []string {
+	words, msg := Tokenize(line)
+	if msg != nil {
+		return nil
+	}
+	current := ""
+	if len(words) > 0 && len(line) > 0 && !strings.ContainsRune(" \t", rune(line[len(line)-1])) {
+		current = words[len(words)-1]
+		words = words[0 : len(words)-1]
+	}
+	candidates := completeWords(ctx, r, words, current)
+	prefix := JoinArgs(words)
+	if len(prefix) > 0 {
+		prefix += " "
+	}
+	lines := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if strings.HasSuffix(c, "/") {
+			lines = append(lines, prefix+QuoteArg(c))
+		} else {
+			lines = append(lines, prefix+QuoteArg(c)+" ")
+		}
+	}
+	return lines
+}
+
+func dedup(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
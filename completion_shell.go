@@ -0,0 +1,95 @@
+package gocli
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// shell补全脚本回调的隐藏指令: prog __complete -- {words...}
+const completeCommandKey = "__complete"
+
+var shells = []string{"bash", "zsh", "fish"}
+
+const bashCompletion = `# bash completion for %[1]s
+# eg: source <(%[1]s completion bash)
+_%[2]s_complete() {
+    local IFS=$'\n'
+    COMPREPLY=( $("${COMP_WORDS[0]}" %[3]s -- "${COMP_WORDS[@]:1:$COMP_CWORD}" 2>/dev/null) )
+    if [[ ${#COMPREPLY[@]} -eq 1 && "${COMPREPLY[0]}" == */ ]]; then
+        compopt -o nospace 2>/dev/null
+    fi
+}
+complete -o default -F _%[2]s_complete %[1]s
+`
+
+const zshCompletion = `#compdef %[1]s
+# zsh completion for %[1]s
+# eg: %[1]s completion zsh > "${fpath[1]}/_%[1]s"
+_%[2]s() {
+    local -a candidates
+    local c
+    candidates=("${(@f)$("${words[1]}" %[3]s -- "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    for c in "${candidates[@]}"; do
+        [[ -z "$c" ]] && continue
+        if [[ "$c" == */ ]]; then
+            compadd -Q -S '' -- "$c"
+        else
+            compadd -Q -- "$c"
+        fi
+    done
+}
+if [ "$funcstack[1]" = "_%[2]s" ]; then
+    _%[2]s "$@"
+else
+    compdef _%[2]s %[1]s
+fi
+`
+
+const fishCompletion = `# fish completion for %[1]s
+# eg: %[1]s completion fish > ~/.config/fish/completions/%[1]s.fish
+function __%[2]s_complete
+    set -l tokens (commandline -opc)
+    set -l current (commandline -ct)
+    set -l prog $tokens[1]
+    set -e tokens[1]
+    $prog %[3]s -- $tokens "$current" 2>/dev/null
+end
+complete -c %[1]s -f -a '(__%[2]s_complete)'
+`
+
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// 生成shell补全脚本,prog为程序名称
+func ShellCompletion(shell string, prog string) (string, error) {
+	fn := nonIdentifier.ReplaceAllString(prog, "_")
+	switch shell {
+	case "bash":
+		return fmt.Sprintf(bashCompletion, prog, fn, completeCommandKey), nil
+	case "zsh":
+		return fmt.Sprintf(zshCompletion, prog, fn, completeCommandKey), nil
+	case "fish":
+		return fmt.Sprintf(fishCompletion, prog, fn, completeCommandKey), nil
+	}
+	return "", fmt.Errorf("不支持的shell:%s,可选:%s", shell, strings.Join(shells, "|"))
+}
+
+// __complete -- {words...}: 启动flag(如-pdir)从待补全的输入中读取
+func completeRequest(args []string) (Args, bool) {
+	if len(args) > 1 && args[0] == completeCommandKey && args[1] == EndOfFlags {
+		return args[2:], true
+	}
+	return nil, false
+}
+
+// shell补全,args最后一个为正在输入的词
+func shellComplete(ctx Context, args Args) []string {
+	if len(args) == 0 {
+		args = Args{""}
+	}
+	var rctx Context = ctx
+	if pctx, ok := ctx.(*pluginContext); ok {
+		rctx = pctx.Context
+	}
+	return completeWords(rctx, registrey(ctx), args[0:len(args)-1], args[len(args)-1])
+}
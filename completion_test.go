@@ -0,0 +1,62 @@
+package gocli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompleteLine(t *testing.T) {
+	r := testRegistry(gogenCore, &GeneralPlugin{
+		ID: "db_plugin",
+		Commands: []Command{
+			echoCommand("db migrate up"),
+			echoCommand("db migrate down"),
+			NewFlagsCommand("db dump", "", echoCommand("db dump").Run,
+				EnumFlag([]string{"sql", "csv"}, "fmt"), StringFlag("out")),
+		},
+	})
+	cases := []struct {
+		line   string
+		expect []string
+	}{
+		{"sho", []string{"show "}},
+		{"show h", []string{"show history "}},
+		{"db m", []string{"db migrate "}},
+		{"db migrate ", []string{"db migrate down ", "db migrate up "}},
+		{"db dump -", []string{"db dump -fmt ", "db dump -out "}},
+		{"db dump -fmt sql -", []string{"db dump -fmt sql -out "}},
+		{"db dump -fmt c", []string{"db dump -fmt csv "}},
+		{"genplugin x.go -ve", []string{"genplugin x.go -ver "}},
+		{"nothing ", []string{}},
+	}
+	for _, c := range cases {
+		got := completeLine(nil, r, c.line)
+		if !reflect.DeepEqual(got, c.expect) {
+			t.Errorf("%q: expect %q, got %q", c.line, c.expect, got)
+		}
+	}
+}
+
+func TestShellCompletion(t *testing.T) {
+	for _, shell := range shells {
+		script, err := ShellCompletion(shell, "my-app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(script, "my-app") || !strings.Contains(script, "_my_app") || !strings.Contains(script, completeCommandKey+" --") {
+			t.Errorf("%s script: %s", shell, script)
+		}
+	}
+	if _, err := ShellCompletion("cmd", "my-app"); err == nil {
+		t.Error("cmd is not supported")
+	}
+	msg := CLI().Run([]string{completeCommandKey, EndOfFlags, "show", "h"})
+	if msg == nil || msg.Msg() != "history" {
+		t.Errorf("expect history, got %+v", msg)
+	}
+	msg = CLI().Run([]string{completeCommandKey, EndOfFlags, "__"})
+	if msg == nil || len(msg.Msg()) > 0 {
+		t.Errorf("hidden command should not be completed, got %+v", msg)
+	}
+}
@@ -55,7 +55,7 @@ type Console interface {
 }
 
 func NewConsole(term io.Writer, logf *Logger) Console {
-	c := &mixedConsole{t: term, log: logf}
+	c := &mixedConsole{t: term, log: logf, ll: new(atomic.Int32)}
 	c.ll.Store(int32(LOG_DEBUG))
 	return c
 }
@@ -64,7 +64,8 @@ type mixedConsole struct {
 	prefix string
 	t      io.Writer
 	log    *Logger
-	ll     atomic.Int32
+	// 派生的Console共享日志级别
+	ll *atomic.Int32
 }
 
 func (console *mixedConsole) NewLogger(file string) Log {
@@ -103,24 +104,28 @@ func (console *mixedConsole) FileLogger() (Console, error) {
 	return &mixedConsole{prefix: console.prefix, log: console.log, ll: console.ll}, nil
 }
 
+func (console *mixedConsole) derive(prefix string) Console {
+	return &mixedConsole{prefix: prefix, t: console.t, log: console.log, ll: console.ll}
+}
+
 func (console *mixedConsole) Prefix(prefix string, args ...any) Console {
 	pre := fmt.Sprintf(prefix, args...)
-	return &mixedConsole{prefix: pre}
+	return console.derive(pre)
 }
 
 func (console *mixedConsole) AppendPrefix(prefix string, args ...any) Console {
 	pre := fmt.Sprintf(prefix, args...)
 	if len(console.prefix) == 0 {
-		return &mixedConsole{prefix: pre}
+		return console.derive(pre)
 	}
-	return &mixedConsole{prefix: fmt.Sprintf("%s %s", console.prefix, pre)}
+	return console.derive(fmt.Sprintf("%s %s", console.prefix, pre))
 }
 func (console *mixedConsole) PrependPrefix(prefix string, args ...any) Console {
 	pre := fmt.Sprintf(prefix, args...)
 	if len(console.prefix) == 0 {
-		return &mixedConsole{prefix: pre}
+		return console.derive(pre)
 	}
-	return &mixedConsole{prefix: fmt.Sprintf("%s %s", pre, console.prefix)}
+	return console.derive(fmt.Sprintf("%s %s", pre, console.prefix))
 }
 
 func (console *mixedConsole) write(ll LogLevel, txt string, args ...any) {
@@ -1,15 +1,20 @@
 package gocli
 
 import (
+	stdctx "context"
 	"path/filepath"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type ContextKey = string
 
 const (
 	history_list ContextKey = "history_*[][]string.registery"
+	output_sink  ContextKey = "output.sink"
+	trust_store  ContextKey = "trust_*TrustStore.registery"
+	load_report  ContextKey = "plugin_*LoadReport.registery"
 	// interupt_signal   ContextKey = "exit.signal"
 	// logfile_path      ContextKey = "logfile.registery"
 	// console_bound     ContextKey = "console.registery"
@@ -24,21 +29,32 @@ type PluginVersionMap = map[string]PluginBundle
 type PluginBundle interface {
 	Version() string
 	Name() string
-	Md5() string
+	// 文件的sha256摘要
+	Digest() string
+	// 签名的可信公钥名称,未验证时为空
+	Signer() string
 	File() string
+	// 解析后的依赖插件
 	Dependencies() []PluginBundle
+	// 声明的依赖及版本约束
+	Requires() map[string]string
 }
 
 type pluginBundle struct {
 	*RegisteredPlugin
 }
 
-func (bundle *pluginBundle) Md5() string {
-	md5 := "N/A"
-	if bundle.RegisteredPlugin.sign != nil {
-		md5 = bundle.sign.digest
+func (bundle *pluginBundle) Digest() string {
+	if bundle.sign == nil {
+		return "N/A"
+	}
+	return bundle.sign.digest
+}
+func (bundle *pluginBundle) Signer() string {
+	if bundle.sign == nil || !bundle.sign.verified {
+		return ""
 	}
-	return md5
+	return bundle.sign.signer
 }
 func (bundle *pluginBundle) File() string {
 	if len(bundle.file) == 0 {
@@ -47,22 +63,101 @@ func (bundle *pluginBundle) File() string {
 	return filepath.Base(bundle.file)
 }
 func (bundle *pluginBundle) Dependencies() []PluginBundle {
-	return make([]PluginBundle, 0)
+	deps := make([]PluginBundle, 0, len(bundle.deps))
+	for _, dep := range bundle.deps {
+		deps = append(deps, &pluginBundle{RegisteredPlugin: dep})
+	}
+	return deps
+}
+func (bundle *pluginBundle) Requires() map[string]string {
+	return requiresOf(bundle.Plugin)
 }
 
+// 实现了标准库的context.Context,可直接传给net/http,database/sql,exec.CommandContext等;
+// 指令超时(-timeout)或被取消(界面中Ctrl+C,中断信号)时 Done 关闭
 type Context interface {
-	Value(key any) any
+	stdctx.Context
 	Interupt() bool
 	WorkDir() string
 	RegisteredPlugins() PluginVersionMap
 	StdConsole() StandConsole
 	Logger() (logger Log, enable bool)
+	// 指令执行过程中的输出,未设置时写到标准输出
+	Output() OutputSink
 	ValueOperator
 }
 
 type registreyContext interface {
 	registry() Registry
+	stdContext() stdctx.Context
+	Context
+}
+
+// 派生可取消的Context,cancel后 Done 关闭,其余与parent相同
+func WithCancel(parent Context) (ctx Context, cancel func()) {
+	return derive(parent, func(std stdctx.Context) (stdctx.Context, stdctx.CancelFunc) {
+		return stdctx.WithCancel(std)
+	})
+}
+
+// 派生超时后取消的Context
+func WithTimeout(parent Context, timeout time.Duration) (ctx Context, cancel func()) {
+	return derive(parent, func(std stdctx.Context) (stdctx.Context, stdctx.CancelFunc) {
+		return stdctx.WithTimeout(std, timeout)
+	})
+}
+
+func derive(parent Context, with func(stdctx.Context) (stdctx.Context, stdctx.CancelFunc)) (Context, func()) {
+	if pctx, ok := parent.(*pluginContext); ok {
+		parent = pctx.Context
+	}
+	rctx, ok := parent.(registreyContext)
+	if !ok {
+		// 外部实现的Context,直接作为标准库context派生
+		std, cancel := with(parent)
+		return &wrappedContext{Context: parent, std: std}, cancel
+	}
+	std, cancel := with(rctx.stdContext())
+	return &cancelContext{registreyContext: rctx, std: std}, cancel
+}
+
+// 由外部实现的Context派生,除取消外与parent相同
+type wrappedContext struct {
 	Context
+	std stdctx.Context
+}
+
+func (ctx *wrappedContext) Deadline() (deadline time.Time, ok bool) {
+	return ctx.std.Deadline()
+}
+
+func (ctx *wrappedContext) Done() <-chan struct{} {
+	return ctx.std.Done()
+}
+
+func (ctx *wrappedContext) Err() error {
+	return ctx.std.Err()
+}
+
+type cancelContext struct {
+	registreyContext
+	std stdctx.Context
+}
+
+func (ctx *cancelContext) stdContext() stdctx.Context {
+	return ctx.std
+}
+
+func (ctx *cancelContext) Deadline() (deadline time.Time, ok bool) {
+	return ctx.std.Deadline()
+}
+
+func (ctx *cancelContext) Done() <-chan struct{} {
+	return ctx.std.Done()
+}
+
+func (ctx *cancelContext) Err() error {
+	return ctx.std.Err()
 }
 
 type ValueOperator interface {
@@ -72,6 +167,7 @@ type ValueOperator interface {
 }
 
 type context struct {
+	std       stdctx.Context
 	values    sync.Map
 	registrey Registry
 	console   Console
@@ -98,6 +194,32 @@ func (ctx *context) Logger() (logger Log, enable bool) {
 	return ctx.console.Log()
 }
 
+func (ctx *context) Output() OutputSink {
+	if sink, ok := ctx.Value(output_sink).(OutputSink); ok {
+		return sink
+	}
+	return stdoutSink
+}
+
+func (ctx *context) stdContext() stdctx.Context {
+	if ctx.std == nil {
+		return stdctx.Background()
+	}
+	return ctx.std
+}
+
+func (ctx *context) Deadline() (deadline time.Time, ok bool) {
+	return ctx.stdContext().Deadline()
+}
+
+func (ctx *context) Done() <-chan struct{} {
+	return ctx.stdContext().Done()
+}
+
+func (ctx *context) Err() error {
+	return ctx.stdContext().Err()
+}
+
 func (ctx *context) Remove(key any) (removed any, loaded bool) {
 	return ctx.values.LoadAndDelete(key)
 }
@@ -115,9 +237,13 @@ func (ctx *context) Interupt() bool {
 	old := ctx.interrupt.Load()
 	return ctx.interrupt.CompareAndSwap(old, true)
 }
+
+// 先查找SetValue设置的值,再查找标准库context中的值
 func (ctx *context) Value(key any) any {
-	v, _ := ctx.values.Load(key)
-	return v
+	if v, ok := ctx.values.Load(key); ok {
+		return v
+	}
+	return ctx.stdContext().Value(key)
 }
 
 func (ctx *context) Console() Console {
@@ -0,0 +1,130 @@
+package gocli
+
+import (
+	stdctx "context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func waitCommand(key string) Command {
+	return NewCommand(key, key, func(ctx Context, args []string, flagmap FlagMap) Message {
+		select {
+		case <-ctx.Done():
+			return WrapMessage(0, ctx.Err(), "已取消")
+		case <-time.After(time.Second):
+			return InfoMessage(0, "timeout")
+		}
+	})
+}
+
+func TestWithCancel(t *testing.T) {
+	ctx := testContext(t, gogenCore, &GeneralPlugin{ID: "wait_plugin", Commands: []Command{waitCommand("wait")}})
+	if ctx.Done() != nil || ctx.Err() != nil {
+		t.Error("root context should never be cancelled")
+	}
+	c, args, fmap, msg := resolveInput(ctx.registry(), Args{"wait"})
+	if msg != nil {
+		t.Fatal(msg.Msg())
+	}
+	jctx, cancel := WithCancel(ctx)
+	result := make(chan Message, 1)
+	go func() {
+		result <- runCommand(jctx, c, args, fmap)
+	}()
+	cancel()
+	select {
+	case msg = <-result:
+	case <-time.After(time.Second / 2):
+		t.Fatal("command should return after cancel")
+	}
+	if err, failed := msg.Err(); !failed || !errors.Is(err, stdctx.Canceled) {
+		t.Errorf("expect canceled, got %+v", msg)
+	}
+	if jctx.Value(history_list) != ctx.Value(history_list) || jctx.(registreyContext).registry() != ctx.registry() {
+		t.Error("cancel context should share values and registry with parent")
+	}
+
+	// 插件Context派生的子Context随父Context取消
+	parent, cancelParent := WithCancel(ctx)
+	child, cancelChild := WithCancel(NewPContext(parent, nil))
+	defer cancelChild()
+	cancelParent()
+	select {
+	case <-child.Done():
+	default:
+		t.Error("child should be cancelled with parent")
+	}
+
+	// 外部实现的Context同样可以派生
+	var foreign struct{ Context }
+	foreign.Context, cancelParent = WithCancel(ctx)
+	child, cancelChild = WithTimeout(foreign, time.Minute)
+	defer cancelChild()
+	if _, ok := child.Deadline(); !ok || child.WorkDir() != ctx.WorkDir() || child.Value(history_list) != ctx.Value(history_list) {
+		t.Error("derived context should keep parent values and add a deadline")
+	}
+	cancelParent()
+	select {
+	case <-child.Done():
+	case <-time.After(time.Second / 2):
+		t.Error("context derived from a foreign parent should be cancelled with it")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	ctx := testContext(t, gogenCore, &GeneralPlugin{ID: "wait_plugin", Commands: []Command{waitCommand("wait")}})
+	c, args, fmap, msg := resolveInput(ctx.registry(), Args{"wait", "-timeout", "20ms"})
+	if msg != nil {
+		t.Fatal(msg.Msg())
+	}
+	msg = runCommand(ctx, c, args, fmap)
+	if err, failed := msg.Err(); !failed || !errors.Is(err, stdctx.DeadlineExceeded) {
+		t.Errorf("expect deadline exceeded, got %+v", msg)
+	}
+	if !strings.Contains(FormatMessage(msg), "-timeout 20ms") || ExitCode(msg) != ExitTimeout {
+		t.Errorf("unexpected timeout message %q", FormatMessage(msg))
+	}
+	if ctx.Err() != nil {
+		t.Error("timeout should not cancel the root context")
+	}
+}
+
+type traceKey struct{}
+
+func TestStdContext(t *testing.T) {
+	ctx := testContext(t)
+	ctx.std = stdctx.WithValue(stdctx.Background(), traceKey{}, "trace-1")
+	var std stdctx.Context = ctx
+	if std.Value(traceKey{}) != "trace-1" {
+		t.Error("Value should fall back to the standard context")
+	}
+	jctx, cancel := WithTimeout(ctx, time.Hour)
+	defer cancel()
+	if _, ok := jctx.Deadline(); !ok || jctx.Value(traceKey{}) != "trace-1" {
+		t.Error("derived context should keep deadline and values")
+	}
+	sub, subCancel := stdctx.WithCancel(jctx)
+	cancel()
+	<-sub.Done()
+	subCancel()
+
+	signals := make(chan os.Signal, 1)
+	released := make(chan struct{})
+	sctx, stop := watchSignals(stdctx.Background(), signals, func() { close(released) })
+	defer stop()
+	signals <- os.Interrupt
+	select {
+	case <-sctx.Done():
+	case <-time.After(time.Second):
+		t.Error("interrupt should cancel the context")
+	}
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Error("signal handling should be released after the first signal")
+	}
+	stop()
+}
@@ -0,0 +1,93 @@
+package gocli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 依赖检查失败的插件:依赖缺失,版本不兼容或循环依赖
+type DependencyError struct {
+	Problems []string
+}
+
+func (e *DependencyError) Error() string {
+	return fmt.Sprintf("插件依赖检查失败: %s", strings.Join(e.Problems, "; "))
+}
+
+// 按依赖拓扑排序,同级按注册顺序;同时记录每个插件解析后的依赖
+func (r *registration) InstallOrder() ([]*RegisteredPlugin, error) {
+	problems := make([]string, 0)
+	for _, name := range r.names {
+		p := r.plugins[name]
+		p.deps = p.deps[:0]
+		if err := checkCore(p.Plugin); err != nil {
+			problems = append(problems, err.Error())
+		}
+		requires := requiresOf(p.Plugin)
+		keys := make([]string, 0, len(requires))
+		for k := range requires {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, dep := range keys {
+			c, err := ParseConstraint(requires[dep])
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s 依赖 %s: %s", name, dep, err.Error()))
+				continue
+			}
+			found, ok := r.plugins[dep]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s 依赖 %s %s: 未找到", name, dep, c))
+				continue
+			}
+			v, err := ParseVersion(found.Version())
+			if err != nil || !c.Check(v) {
+				problems = append(problems, fmt.Sprintf("%s 依赖 %s %s: 版本%s不兼容", name, dep, c, found.Version()))
+				continue
+			}
+			p.deps = append(p.deps, found)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &DependencyError{Problems: problems}
+	}
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(r.names))
+	order := make([]*RegisteredPlugin, 0, len(r.names))
+	var visit func(p *RegisteredPlugin, path []string) error
+	visit = func(p *RegisteredPlugin, path []string) error {
+		name := p.Name()
+		path = append(path, name)
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			for i := range path {
+				if path[i] == name {
+					path = path[i:]
+					break
+				}
+			}
+			return &DependencyError{Problems: []string{fmt.Sprintf("循环依赖 %s", strings.Join(path, " -> "))}}
+		}
+		state[name] = visiting
+		for _, dep := range p.deps {
+			if err := visit(dep, path); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		order = append(order, p)
+		return nil
+	}
+	for _, name := range r.names {
+		if err := visit(r.plugins[name], nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
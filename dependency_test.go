@@ -0,0 +1,128 @@
+package gocli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func dependentPlugin(name string, version string, deps map[string]string) Plugin {
+	return &GeneralPlugin{ID: name, Ver: version, Deps: deps}
+}
+
+func TestInstallOrder(t *testing.T) {
+	r := testRegistry(
+		dependentPlugin("web_plugin", "1.0.0", map[string]string{"db_plugin": ">=1.2.0", "cache_plugin": ""}),
+		dependentPlugin("cache_plugin", "0.3.0", map[string]string{"db_plugin": "<2"}),
+		dependentPlugin("db_plugin", "1.3.0", nil),
+	)
+	plugins, err := r.InstallOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(plugins))
+	for _, p := range plugins {
+		names = append(names, p.Name())
+	}
+	if strings.Join(names, ",") != "db_plugin,cache_plugin,web_plugin" {
+		t.Errorf("unexpected order %v", names)
+	}
+
+	var derr *DependencyError
+	_, err = testRegistry(
+		dependentPlugin("web_plugin", "1.0.0", map[string]string{"db_plugin": ">=1.2.0", "auth_plugin": "1"}),
+		dependentPlugin("db_plugin", "1.1.0", nil),
+	).InstallOrder()
+	if !errors.As(err, &derr) || len(derr.Problems) != 2 ||
+		!strings.Contains(err.Error(), "auth_plugin 1: 未找到") || !strings.Contains(err.Error(), "db_plugin >=1.2.0: 版本1.1.0不兼容") {
+		t.Errorf("expect missing and incompatible dependency, got %v", err)
+	}
+
+	_, err = testRegistry(
+		dependentPlugin("a_plugin", "1.0.0", map[string]string{"b_plugin": ""}),
+		dependentPlugin("b_plugin", "1.0.0", map[string]string{"c_plugin": ""}),
+		dependentPlugin("c_plugin", "1.0.0", map[string]string{"b_plugin": ""}),
+	).InstallOrder()
+	if err == nil || !strings.Contains(err.Error(), "循环依赖 b_plugin -> c_plugin -> b_plugin") {
+		t.Errorf("expect cycle, got %v", err)
+	}
+}
+
+func TestSetupOrder(t *testing.T) {
+	ctx := testContext(t)
+	setup := make([]string, 0, 2)
+	plugin := func(name string, deps map[string]string) Plugin {
+		return &GeneralPlugin{ID: name, Ver: "1.0.0", Deps: deps, Init: func(ctx Context) error {
+			setup = append(setup, name)
+			return nil
+		}}
+	}
+	ctx.registry().RegisterPlugins(plugin("web_plugin", map[string]string{"db_plugin": ">=1"}), plugin("db_plugin", nil))
+	if err := CLI().registerPlugin(ctx, false, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(setup, ",") != "db_plugin,web_plugin" {
+		t.Errorf("unexpected setup order %v", setup)
+	}
+	msg := _plugins.Run(NewPContext(ctx, nil), nil, NewFlagMap())
+	if !strings.Contains(msg.Msg(), "web_plugin") || !strings.Contains(msg.Msg(), "└─ db_plugin >=1 (1.0.0)") {
+		t.Errorf("show plugins should display dependencies:\n%s", msg.Msg())
+	}
+	text, _ := Render(msg.(DataMessage).Data(), OutputJson)
+	if !strings.Contains(text, `"depends": [`) {
+		t.Errorf("json output should contain depends:\n%s", text)
+	}
+
+	ctx = testContext(t)
+	ctx.registry().RegisterPlugins(plugin("web_plugin", map[string]string{"db_plugin": ">=1"}))
+	var derr *DependencyError
+	if err := CLI().registerPlugin(ctx, false, nil, nil); !errors.As(err, &derr) {
+		t.Errorf("expect dependency error, got %v", err)
+	}
+}
+
+func TestVersionCheck(t *testing.T) {
+	_, err := testRegistry(
+		&GeneralPlugin{ID: "future_plugin", Ver: "1.0.0", MinCore: "99.0.0"},
+		&GeneralPlugin{ID: "current_plugin", Ver: "1.0.0", MinCore: CoreVersion},
+	).InstallOrder()
+	if err == nil || !strings.Contains(err.Error(), "future_plugin要求核心版本>=99.0.0") || strings.Contains(err.Error(), "current_plugin") {
+		t.Errorf("expect core version error, got %v", err)
+	}
+	cases := []struct {
+		min  string
+		core string
+		ok   bool
+	}{
+		{"1.2.0", "1.2.0", true},
+		{"1.2.0", "1.9.3", true},
+		{"1.2.0", "1.1.0", false},
+		{"1.2.0", "2.0.0", false},
+		{"2.0.0", "1.9.0", false},
+		// 0.x只要求不低于min,核心升级patch,minor不会拒绝模板生成的插件
+		{"0.0.1", "0.0.2", true},
+		{"0.0.1", "0.3.0", true},
+		{"0.0.1", "1.0.0", true},
+		{"0.2.0", "0.1.9", false},
+	}
+	for _, c := range cases {
+		err := checkCoreVersion(&GeneralPlugin{ID: "p", MinCore: c.min}, c.core)
+		if (err == nil) != c.ok {
+			t.Errorf("MinCore %s on core %s: expect %v, got %v", c.min, c.core, c.ok, err)
+		}
+	}
+	if v := (&GeneralPlugin{}).Version(); v != "0.0.0" {
+		t.Errorf("default version should be 0.0.0, got %s", v)
+	}
+	r := testRegistry()
+	err = r.RegisterPlugins(&GeneralPlugin{ID: "bad_plugin", Ver: "latest"}, &GeneralPlugin{ID: "good_plugin", Ver: "1.0.0"})
+	if err == nil || !strings.Contains(err.Error(), "无效的版本") {
+		t.Errorf("expect invalid version error, got %v", err)
+	}
+	if _, ok := r.Plugin("bad_plugin"); ok {
+		t.Error("plugin with invalid version should not be registered")
+	}
+	if _, ok := r.Plugin("good_plugin"); !ok {
+		t.Error("valid plugins should still be registered")
+	}
+}
@@ -0,0 +1,77 @@
+package gocli
+
+import (
+	stdctx "context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// 进程退出码,由 ExitCode 按 Message 的code及kind转换
+const (
+	ExitOK         = 0   // 成功,或正常退出交互界面
+	ExitFailure    = 1   // 指令执行出错(kind为error)
+	ExitUsage      = 2   // 用法错误:CodeUsage,CodeAmbiguous
+	ExitValidation = 65  // 参数或flag值校验失败:CodeInvalid
+	ExitPlugin     = 69  // 插件加载或初始化失败:CodePlugin
+	ExitTimeout    = 124 // 指令超时:-timeout
+	ExitNotFound   = 127 // 指令不存在:CodeNotFound
+	ExitCancelled  = 130 // 收到中断信号取消
+)
+
+// Message转换为进程退出码;code<0表示中断,只有kind为error时视为失败
+func ExitCode(msg Message) int {
+	if msg == nil {
+		return ExitOK
+	}
+	switch msg.Code() {
+	case CodeUsage, CodeAmbiguous:
+		return ExitUsage
+	case CodeNotFound:
+		return ExitNotFound
+	case CodeInvalid:
+		return ExitValidation
+	case CodePlugin:
+		return ExitPlugin
+	}
+	if msg.Code() < 0 {
+		if msg.Kind() > LOG_WARN {
+			return ExitFailure
+		}
+		return ExitOK
+	}
+	if err, failed := msg.Err(); failed {
+		switch {
+		case errors.Is(err, stdctx.DeadlineExceeded):
+			return ExitTimeout
+		case errors.Is(err, stdctx.Canceled):
+			return ExitCancelled
+		}
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// 执行os.Args,输出结果后按 ExitCode 退出进程
+func (boot *BootStrap) Main() {
+	os.Exit(printResult(boot.Run(os.Args[1:]), os.Stdout, os.Stderr))
+}
+
+// 失败时输出到stderr,中断(如quit)的提示已在交互界面中显示,不再输出;
+// -o json|yaml 时标准输出只有数据,提示等写到stderr
+func printResult(msg Message, stdout io.Writer, stderr io.Writer) int {
+	code := ExitCode(msg)
+	if msg == nil || len(msg.Msg()) == 0 || (msg.Code() < 0 && code == ExitOK) {
+		return code
+	}
+	out := stdout
+	if code != ExitOK {
+		out = stderr
+	}
+	fmt.Fprintln(out, FormatMessage(msg))
+	if notes := MessageNotes(msg); len(notes) > 0 && isStructured(msg) {
+		fmt.Fprintln(stderr, notes)
+	}
+	return code
+}
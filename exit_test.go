@@ -0,0 +1,69 @@
+package gocli
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	r := testRegistry(gogenCore)
+	resolve := func(input ...string) Message {
+		_, _, _, msg := resolveInput(r, input)
+		return msg
+	}
+	tokenize := func(input string) Message {
+		_, msg := Tokenize(input)
+		return msg
+	}
+	cases := []struct {
+		msg  Message
+		code int
+	}{
+		{nil, ExitOK},
+		{InfoMessage(0, "done"), ExitOK},
+		{WarnMessage(0, "careful"), ExitOK},
+		{ErrMessage(0, "failed"), ExitFailure},
+		{InteruptMessage("退出程序"), ExitOK},
+		{ErrMessage(-1, "获取logger失败"), ExitFailure},
+		{resolve("nothing"), ExitNotFound},
+		{resolve("s"), ExitUsage},
+		{resolve("genplugin", "a.go"), ExitUsage},
+		{resolve("help", "-logl", "x"), ExitValidation},
+		{tokenize(`show "plugins`), ExitUsage},
+		{ErrMessage(CodePlugin, "setup failed"), ExitPlugin},
+	}
+	for _, c := range cases {
+		if code := ExitCode(c.msg); code != c.code {
+			t.Errorf("%+v: expect exit code %d, got %d", c.msg, c.code, code)
+		}
+	}
+}
+
+func TestPrintResult(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := printResult(InfoMessage(0, "ok"), &stdout, &stderr); code != ExitOK || stdout.String() != "ok\n" || stderr.Len() > 0 {
+		t.Errorf("unexpected output %d %q %q", code, stdout.String(), stderr.String())
+	}
+	stdout.Reset()
+	if code := printResult(WarnMessage(CodeNotFound, "command not found"), &stdout, &stderr); code != ExitNotFound || stderr.String() != "command not found\n" || stdout.Len() > 0 {
+		t.Errorf("unexpected output %d %q %q", code, stdout.String(), stderr.String())
+	}
+	stderr.Reset()
+	if code := printResult(InteruptMessage("退出程序"), &stdout, &stderr); code != ExitOK || stdout.Len()+stderr.Len() > 0 {
+		t.Errorf("interrupt should print nothing, got %q %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestPluginSetupFailure(t *testing.T) {
+	ctx := testContext(t)
+	cause := errors.New("no database")
+	ctx.registry().RegisterPlugins(&GeneralPlugin{ID: "broken_plugin", Init: func(ctx Context) error {
+		return cause
+	}})
+	err := CLI().registerPlugin(ctx, false, nil, nil)
+	if !errors.Is(err, cause) || !strings.Contains(err.Error(), "broken_plugin setup") {
+		t.Fatalf("expect setup error, got %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 type Flag interface {
@@ -11,13 +12,29 @@ type Flag interface {
 	Alias() (string, bool)
 	Usage() string
 }
+
+// flag值个数,0为开关flag,大于0为固定个数
+const ArityVariadic = -1
+
+// 声明flag接受的值个数,未实现则按ArityVariadic处理
+type FlagArity interface {
+	Arity() int
+}
+
+func arityOf(f Flag) int {
+	if a, ok := f.(FlagArity); ok {
+		return a.Arity()
+	}
+	return ArityVariadic
+}
+
 type FParser[T any] interface {
 	Parse(args Args) (T, bool)
 }
 
 func NewFlag(nameUsageAlias ...string) Flag {
 	size := len(nameUsageAlias)
-	flag := flagOption[Args]{}
+	flag := flagOption[Args]{arity: ArityVariadic}
 	if size > 0 {
 		flag.name = nameUsageAlias[0]
 	}
@@ -31,8 +48,15 @@ func NewFlag(nameUsageAlias ...string) Flag {
 	return &flag
 }
 
+// arity: 0开关flag,N固定个数,ArityVariadic不限
+func NewArityFlag(arity int, nameUsageAlias ...string) Flag {
+	flag := NewFlag(nameUsageAlias...).(*flagOption[Args])
+	flag.arity = arity
+	return flag
+}
+
 func BuildFlag[T any](name string, alias string, parser FlagParser[T]) Flag {
-	return &flagOption[T]{name: name, alias: alias, parser: parser}
+	return &flagOption[T]{name: name, alias: alias, parser: parser, arity: ArityVariadic}
 }
 
 type FlagParser[T any] func(args Args) (T, bool)
@@ -42,6 +66,11 @@ type flagOption[T any] struct {
 	alias  string
 	parser func(args Args) (T, bool)
 	usage  string
+	arity  int
+}
+
+func (opt *flagOption[T]) Arity() int {
+	return opt.arity
 }
 
 func (opt *flagOption[T]) Usage() string {
@@ -58,6 +87,10 @@ func (opt *flagOption[T]) Alias() (string, bool) {
 	return "", false
 }
 func (opt *flagOption[T]) Parse(args Args) (T, bool) {
+	if opt.parser == nil {
+		v, ok := any(args).(T)
+		return v, ok
+	}
 	return opt.parser(args)
 }
 
@@ -70,14 +103,16 @@ type FlagMap interface {
 	HasFlag(flag Flag) (Args, bool)
 	Empty() bool
 	toArgs() Args
+	keys() []string
 }
 
+// 值无法解析时返回false,需要错误信息时使用FlagValue
 func ParseFlag[T any](fm FlagMap, f Flag) (T, bool) {
 	args, ok := fm.HasFlag(f)
 	var v T
 	if ok {
 		if p, canParse := f.(FParser[T]); canParse {
-			v, _ = p.Parse(args)
+			v, ok = p.Parse(args)
 		}
 	}
 	return v, ok
@@ -87,27 +122,150 @@ func NewFlagMap() FlagMap {
 	return &flagArgs{args: make(map[string][]string, 3)}
 }
 
+// 未声明flag的解析,flag之后的值都归属该flag
 func NewFMap(args []string) FlagMap {
-	fmap := make(map[string][]string, len(args)/2)
-	fargIndex := -1
-	var flag string
-	for i := range args {
-		if strings.HasPrefix(args[i], "-") {
-			if fargIndex > -1 {
-				fmap[flag] = args[fargIndex+1 : i]
+	fmap, _ := ParseFlags(nil, args)
+	return fmap
+}
+
+// 只解析flag部分,参见ParseArgs;flag之外多余的值视为错误
+func ParseFlags(flags []Flag, args []string) (FlagMap, Message) {
+	rest, fmap, msg := ParseArgs(flags, args)
+	if msg == nil && len(rest) > 0 {
+		msg = ErrMessage(CodeUsage, "无效的输入:%s", JoinArgs(rest))
+	}
+	return fmap, msg
+}
+
+// 按声明的flags解析输入,参数与flag可交替出现:
+// 支持 -name=value,--alias=value,合并的单字母开关flag(-abc 即 -a -b -c),
+// 以及形如 -5 的负数值; 固定个数的flag只取对应个数的值,其余为参数;
+// ArityVariadic及未声明的flag取其后直到下一个flag的所有值; EndOfFlags之后都是参数
+func ParseArgs(flags []Flag, tokens []string) (Args, FlagMap, Message) {
+	args := make(Args, 0, len(tokens))
+	fa := &flagArgs{args: make(map[string]Args, len(tokens)/2)}
+	spec := newFlagSpec(flags)
+	var (
+		key   string
+		arity int
+		got   int
+	)
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok == EndOfFlags {
+			args = append(args, tokens[i+1:]...)
+			break
+		}
+		if !spec.isFlag(tok) {
+			if len(key) > 0 && (arity == ArityVariadic || got < arity) {
+				fa.args[key] = append(fa.args[key], tok)
+				got++
+			} else {
+				args = append(args, tok)
 			}
-			flag = args[i]
-			fargIndex = i
+			continue
 		}
-	}
-	if fargIndex > -1 {
-		if fargIndex+1 < len(args) {
-			fmap[flag] = args[fargIndex+1:]
+		if arity > 0 && got < arity {
+			return args, fa, ErrMessage(CodeUsage, "flag %s 需要%d个值,输入:%d", key, arity, got)
+		}
+		name, value, attached := spec.split(tok)
+		if f, ok := spec.lookup(name); ok {
+			key, arity = f.Name(), arityOf(f)
+		} else if bundle, ok := spec.bundle(name); ok && !attached {
+			for _, f := range bundle[:len(bundle)-1] {
+				fa.add(f.Name())
+			}
+			last := bundle[len(bundle)-1]
+			key, arity = last.Name(), arityOf(last)
 		} else {
-			fmap[flag] = make([]string, 0)
+			key, arity = name, ArityVariadic
+		}
+		got = 0
+		fa.add(key)
+		if attached {
+			fa.args[key] = append(fa.args[key], value)
+			got++
+		}
+	}
+	if arity > 0 && got < arity {
+		return args, fa, ErrMessage(CodeUsage, "flag %s 需要%d个值,输入:%d", key, arity, got)
+	}
+	return args, fa, nil
+}
+
+type flagSpec struct {
+	flags map[string]Flag
+}
+
+func newFlagSpec(flags []Flag) *flagSpec {
+	spec := &flagSpec{flags: make(map[string]Flag, len(flags)*2)}
+	// 同名时先声明的优先,指令自身的flag覆盖启动flag
+	for i := range flags {
+		f := flags[i]
+		names := []string{f.Name()}
+		if alias, ok := f.Alias(); ok {
+			names = append(names, alias)
 		}
+		for _, name := range names {
+			if _, exist := spec.flags[name]; !exist {
+				spec.flags[name] = f
+			}
+		}
+	}
+	return spec
+}
+
+func (spec *flagSpec) lookup(name string) (Flag, bool) {
+	f, ok := spec.flags[name]
+	return f, ok
+}
+
+func (spec *flagSpec) isFlag(tok string) bool {
+	if len(tok) < 2 || tok[0] != '-' {
+		return false
+	}
+	name, _, _ := spec.split(tok)
+	if _, ok := spec.flags[name]; ok {
+		return true
+	}
+	return !isNegativeNumber(tok)
+}
+
+func isNegativeNumber(tok string) bool {
+	if len(tok) < 2 || tok[0] != '-' || !(tok[1] == '.' || unicode.IsDigit(rune(tok[1]))) {
+		return false
+	}
+	_, err := strconv.ParseFloat(tok, 64)
+	return err == nil
+}
+
+func (spec *flagSpec) split(tok string) (name string, value string, attached bool) {
+	pos := strings.Index(tok, "=")
+	if pos < 0 {
+		return tok, "", false
+	}
+	name = tok[0:pos]
+	if _, ok := spec.flags[tok]; ok || len(strings.TrimLeft(name, "-")) == 0 {
+		return tok, "", false
 	}
-	return &flagArgs{args: fmap}
+	return name, tok[pos+1:], true
+}
+
+// -abc 拆分为 -a -b -c,除最后一个外都必须是开关flag
+func (spec *flagSpec) bundle(name string) ([]Flag, bool) {
+	if len(name) < 3 || strings.HasPrefix(name, "--") {
+		return nil, false
+	}
+	letters := []rune(name[1:])
+	bundle := make([]Flag, 0, len(letters))
+	for i := range letters {
+		f, ok := spec.flags[fmt.Sprintf("-%c", letters[i])]
+		if !ok || (i < len(letters)-1 && arityOf(f) != 0) {
+			return nil, false
+		}
+		bundle = append(bundle, f)
+	}
+	return bundle, true
 }
 
 func fmapToString(fmap FlagMap) string {
@@ -170,11 +328,16 @@ func (fa *flagArgs) GetStrings(key string) (v Args, found bool) {
 	v, found = fa.args[key]
 	return
 }
+
+// 只有flag没有值时为true
 func (fa *flagArgs) GetBool(key string) (v bool, found bool) {
 	args, ok := fa.args[key]
 	found = ok
+	if ok {
+		v = true
+	}
 	if len(args) > 0 {
-		v = strings.Contains("|1|t|T|Y|y|yes|YES|TRUE|true|", fmt.Sprintf("|%s|", args[0]))
+		v, _ = parseBool(args[0])
 	}
 	return
 }
@@ -183,12 +346,25 @@ func (fa *flagArgs) GetInt(key string) (v int, found bool) {
 	args, ok := fa.args[key]
 	found = ok
 	if len(args) > 0 {
-		i, _ := strconv.ParseInt(args[0], 10, 32)
-		v = int(i)
+		v, _ = strconv.Atoi(args[0])
 	}
 	return
 }
 
+func (fa *flagArgs) keys() []string {
+	keys := make([]string, 0, len(fa.args))
+	for k := range fa.args {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (fa *flagArgs) add(key string) {
+	if _, ok := fa.args[key]; !ok {
+		fa.args[key] = make(Args, 0, 1)
+	}
+}
+
 func (fa *flagArgs) Set(key string, values ...string) {
 	fa.args[key] = values
 }
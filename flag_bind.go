@@ -0,0 +1,173 @@
+package gocli
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// 结构体字段tag:
+//
+//	flag:"nm,alias=name" usage:"插件名称" default:"v0.0.1" required:"true" env:"PLUGIN_NAME" enum:"a|b"
+//
+// 支持字段类型: string,int,int64,float64,bool,time.Duration,[]string,map[string]string
+const (
+	tag_flag     = "flag"
+	tag_usage    = "usage"
+	tag_default  = "default"
+	tag_required = "required"
+	tag_env      = "env"
+	tag_enum     = "enum"
+)
+
+type BindFunc[T any] func(ctx Context, args []string, opts *T) Message
+
+type fieldBinder struct {
+	index []int
+	flag  FlagDefinition
+	set   func(fm FlagMap, field reflect.Value) Message
+}
+
+// 解析结构体T的tag,生成flags
+func StructFlags[T any]() ([]Flag, error) {
+	binders, err := structBinders(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	flags := make([]Flag, len(binders))
+	for i := range binders {
+		flags[i] = binders[i].flag
+	}
+	return flags, nil
+}
+
+// 按结构体T的tag读取flags,填充后调用do;tag无效时panic
+func BindRun[T any](do BindFunc[T]) ExecFunc {
+	binders, err := structBinders(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		panic(err.Error())
+	}
+	return func(ctx Context, args []string, flagmap FlagMap) Message {
+		opts := new(T)
+		v := reflect.ValueOf(opts).Elem()
+		for i := range binders {
+			b := binders[i]
+			if msg := b.set(flagmap, v.FieldByIndex(b.index)); msg != nil {
+				return msg
+			}
+		}
+		return do(ctx, args, opts)
+	}
+}
+
+// flags由结构体T的tag生成,等同于 NewFlagsCommand(key, usage, BuildRun(BindRun(do), inputRules, paramRules...), flags...)
+func NewBindCommand[T any](key string, usage string, do BindFunc[T], inputRules []InputValidator, paramRules ...ParamValiator) Command {
+	flags, err := StructFlags[T]()
+	if err != nil {
+		panic(err.Error())
+	}
+	return NewFlagsCommand(key, usage, BuildRun(BindRun(do), inputRules, paramRules...), flags...)
+}
+
+func structBinders(t reflect.Type) ([]*fieldBinder, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s不是结构体", t)
+	}
+	binders := make([]*fieldBinder, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup(tag_flag)
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+		b, err := fieldBinding(field, tag)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s:%s", t.Name(), field.Name, err.Error())
+		}
+		b.index = field.Index
+		binders = append(binders, b)
+	}
+	return binders, nil
+}
+
+func fieldBinding(field reflect.StructField, tag string) (*fieldBinder, error) {
+	parts := strings.Split(tag, ",")
+	name := strings.TrimSpace(parts[0])
+	if len(name) == 0 {
+		return nil, fmt.Errorf("flag名称不能为空")
+	}
+	var alias string
+	for _, p := range parts[1:] {
+		p = strings.TrimSpace(p)
+		if strings.HasPrefix(p, "alias=") {
+			alias = strings.TrimPrefix(p, "alias=")
+		}
+	}
+	desc := []string{name, field.Tag.Get(tag_usage), alias}
+	enum, isEnum := field.Tag.Lookup(tag_enum)
+	switch {
+	case isEnum && field.Type.Kind() == reflect.String:
+		return bindFlag(EnumFlag(strings.Split(enum, "|"), desc...), field)
+	case field.Type == reflect.TypeOf(time.Duration(0)):
+		return bindFlag(DurationFlag(desc...), field)
+	}
+	switch field.Type.Kind() {
+	case reflect.String:
+		return bindFlag(StringFlag(desc...), field)
+	case reflect.Int:
+		return bindFlag(IntFlag(desc...), field)
+	case reflect.Int64:
+		return bindFlag(Int64Flag(desc...), field)
+	case reflect.Float64:
+		return bindFlag(FloatFlag(desc...), field)
+	case reflect.Bool:
+		return bindFlag(BoolFlag(desc...), field)
+	case reflect.Slice:
+		if field.Type.Elem().Kind() == reflect.String {
+			return bindFlag(StringsFlag(desc...), field)
+		}
+	case reflect.Map:
+		if field.Type.Key().Kind() == reflect.String && field.Type.Elem().Kind() == reflect.String {
+			return bindFlag(MapFlag(desc...), field)
+		}
+	}
+	return nil, fmt.Errorf("不支持的flag字段类型%s", field.Type)
+}
+
+func bindFlag[T any](f *ValueFlag[T], field reflect.StructField) (*fieldBinder, error) {
+	if v, ok := field.Tag.Lookup(tag_default); ok {
+		values := Args{v}
+		if f.Arity() == ArityVariadic {
+			values = strings.Split(v, ",")
+		}
+		def, err := f.Convert(values)
+		if err != nil {
+			return nil, fmt.Errorf("默认值无效:%s", err.Error())
+		}
+		f.Default(def)
+	}
+	if v, ok := field.Tag.Lookup(tag_required); ok {
+		required, err := parseBool(v)
+		if err != nil {
+			return nil, err
+		}
+		if required {
+			f.Require()
+		}
+	}
+	if v, ok := field.Tag.Lookup(tag_env); ok {
+		f.Env(v)
+	}
+	return &fieldBinder{
+		flag: f,
+		set: func(fm FlagMap, field reflect.Value) Message {
+			v, msg := FlagValue(fm, f)
+			if msg != nil {
+				return msg
+			}
+			field.Set(reflect.ValueOf(v).Convert(field.Type()))
+			return nil
+		},
+	}, nil
+}
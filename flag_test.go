@@ -0,0 +1,160 @@
+package gocli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	num := NewArityFlag(1, "n", "数量")
+	out := NewArityFlag(1, "out", "输出文件", "output")
+	a := NewArityFlag(0, "a")
+	b := NewArityFlag(0, "b")
+	c := NewArityFlag(1, "c")
+	flags := []Flag{num, out, a, b, c}
+
+	cases := []struct {
+		input  Args
+		expect map[string]Args
+	}{
+		{Args{"-n", "-5"}, map[string]Args{"-n": {"-5"}}},
+		{Args{"-n=-5"}, map[string]Args{"-n": {"-5"}}},
+		{Args{"--output=file.txt"}, map[string]Args{"-out": {"file.txt"}}},
+		{Args{"-out", "a=b"}, map[string]Args{"-out": {"a=b"}}},
+		{Args{"-ab"}, map[string]Args{"-a": {}, "-b": {}}},
+		{Args{"-abc", "x"}, map[string]Args{"-a": {}, "-b": {}, "-c": {"x"}}},
+		{Args{"-x", "1", "-2", "-n", "3"}, map[string]Args{"-x": {"1", "-2"}, "-n": {"3"}}},
+		{Args{"-unknown=v"}, map[string]Args{"-unknown": {"v"}}},
+	}
+	for _, cs := range cases {
+		fmap, msg := ParseFlags(flags, cs.input)
+		if msg != nil {
+			t.Fatalf("%q: %s", cs.input, msg.Msg())
+		}
+		got := fmap.(*flagArgs).args
+		if !reflect.DeepEqual(got, cs.expect) {
+			t.Errorf("%q: expect %q, got %q", cs.input, cs.expect, got)
+		}
+	}
+	if _, msg := ParseFlags(flags, Args{"-n"}); msg == nil {
+		t.Error("missing value of -n should fail")
+	}
+	if _, msg := ParseFlags(flags, Args{"-n", "-a"}); msg == nil {
+		t.Error("flag as value of -n should fail")
+	}
+}
+
+func TestParseArgsInterleaved(t *testing.T) {
+	force := NewArityFlag(0, "f", "覆盖")
+	mode := NewArityFlag(1, "m", "权限")
+	pair := NewArityFlag(2, "p", "键值")
+	tags := NewArityFlag(ArityVariadic, "t", "标签")
+	flags := []Flag{force, mode, pair, tags}
+
+	cases := []struct {
+		input Args
+		args  Args
+		flags map[string]Args
+	}{
+		{Args{"copy", "-f", "a.txt", "dest/"}, Args{"copy", "a.txt", "dest/"}, map[string]Args{"-f": {}}},
+		{Args{"copy", "a.txt", "-m", "644", "dest/"}, Args{"copy", "a.txt", "dest/"}, map[string]Args{"-m": {"644"}}},
+		{Args{"-p", "k", "v", "x", "-t", "a", "b", "-f", "y"}, Args{"x", "y"}, map[string]Args{"-p": {"k", "v"}, "-t": {"a", "b"}, "-f": {}}},
+		{Args{"a", "-t", "b", "--", "-f", "c"}, Args{"a", "-f", "c"}, map[string]Args{"-t": {"b"}}},
+	}
+	for _, cs := range cases {
+		args, fmap, msg := ParseArgs(flags, cs.input)
+		if msg != nil {
+			t.Fatalf("%q: %s", cs.input, msg.Msg())
+		}
+		if !reflect.DeepEqual(args, cs.args) {
+			t.Errorf("%q: expect args %q, got %q", cs.input, cs.args, args)
+		}
+		if got := fmap.(*flagArgs).args; !reflect.DeepEqual(got, cs.flags) {
+			t.Errorf("%q: expect flags %q, got %q", cs.input, cs.flags, got)
+		}
+	}
+	if _, _, msg := ParseArgs(flags, Args{"-p", "k", "-f"}); msg == nil {
+		t.Error("-p with one value should fail")
+	}
+}
+
+func TestTypedFlags(t *testing.T) {
+	num := IntFlag("n", "数量").Default(3)
+	wait := DurationFlag("w", "等待").Require()
+	mode := EnumFlag([]string{"fast", "slow"}, "m", "模式")
+	verbose := BoolFlag("v", "详细")
+	labels := MapFlag("l", "标签")
+	home := StringFlag("home", "目录").Env("GOCLI_TEST_HOME")
+	flags := []Flag{num, wait, mode, verbose, labels, home}
+
+	_, fmap, msg := ParseArgs(flags, Args{"-w", "2s", "-v", "-l", "a=1", "b=2", "-m", "slow"})
+	if msg == nil {
+		msg = ResolveFlags(flags, fmap)
+	}
+	if msg != nil {
+		t.Fatal(msg.Msg())
+	}
+	if v, msg := FlagValue(fmap, num); msg != nil || v != 3 {
+		t.Errorf("default of -n: %d %v", v, msg)
+	}
+	if v, _ := FlagValue(fmap, verbose); !v {
+		t.Error("-v should be true")
+	}
+	if v, _ := FlagValue(fmap, labels); !reflect.DeepEqual(v, map[string]string{"a": "1", "b": "2"}) {
+		t.Errorf("unexpected -l %v", v)
+	}
+	t.Setenv("GOCLI_TEST_HOME", "/tmp/home")
+	if v, _ := FlagValue(fmap, home); v != "/tmp/home" {
+		t.Errorf("env fallback of -home: %s", v)
+	}
+
+	_, fmap, _ = ParseArgs(flags, Args{"-n", "x", "-w", "1s"})
+	if _, msg := FlagValue(fmap, num); msg == nil {
+		t.Error("-n x should fail")
+	}
+	if msg := ResolveFlags(flags, fmap); msg == nil {
+		t.Error("resolve should report -n x")
+	}
+	_, fmap, _ = ParseArgs(flags, Args{"-m", "other"})
+	if msg := ResolveFlags(flags, fmap); msg == nil {
+		t.Error("missing required -w should fail")
+	}
+}
+
+func TestBindRun(t *testing.T) {
+	type options struct {
+		Name    string            `flag:"nm,alias=name" usage:"名称" required:"true"`
+		Version string            `flag:"ver" default:"v0.0.1"`
+		Retry   int               `flag:"r" default:"2"`
+		Mode    string            `flag:"m" enum:"fast|slow" default:"fast"`
+		Tags    []string          `flag:"t"`
+		Labels  map[string]string `flag:"l"`
+		Force   bool              `flag:"f"`
+		skipped string
+	}
+	flags, err := StructFlags[options]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flags) != 7 {
+		t.Fatalf("expect 7 flags, got %d", len(flags))
+	}
+	var bound *options
+	run := BindRun(func(ctx Context, args []string, opts *options) Message {
+		bound = opts
+		return nil
+	})
+	_, fmap, _ := ParseArgs(flags, Args{"--name=demo", "-t", "a", "b", "-f", "-l", "k=v"})
+	if msg := run(MockContext(), nil, fmap); msg != nil {
+		t.Fatal(msg.Msg())
+	}
+	expect := &options{Name: "demo", Version: "v0.0.1", Retry: 2, Mode: "fast",
+		Tags: []string{"a", "b"}, Labels: map[string]string{"k": "v"}, Force: true}
+	if !reflect.DeepEqual(bound, expect) {
+		t.Errorf("expect %+v, got %+v", expect, bound)
+	}
+	_, fmap, _ = ParseArgs(flags, Args{"-ver", "v1"})
+	if msg := run(MockContext(), nil, fmap); msg == nil {
+		t.Error("missing required -nm should fail")
+	}
+}
@@ -0,0 +1,333 @@
+package gocli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 带类型,默认值,必填及环境变量的flag定义
+type FlagDefinition interface {
+	Flag
+	FlagArity
+	Required() bool
+	EnvKey() (string, bool)
+	DefValue() (any, bool)
+	TypeName() string
+	// 校验输入值能否转换
+	Check(values Args) error
+}
+
+// 可选值固定的flag,用于补全及帮助信息
+type FlagOptions interface {
+	Options() []string
+}
+
+type FlagConverter[T any] func(values Args) (T, error)
+
+type ValueFlag[T any] struct {
+	name     string
+	alias    string
+	usage    string
+	typ      string
+	arity    int
+	required bool
+	env      string
+	def      T
+	hasDef   bool
+	options  []string
+	convert  FlagConverter[T]
+}
+
+func NewValueFlag[T any](typ string, arity int, convert FlagConverter[T], nameUsageAlias ...string) *ValueFlag[T] {
+	f := &ValueFlag[T]{typ: typ, arity: arity, convert: convert}
+	size := len(nameUsageAlias)
+	if size > 0 {
+		f.name = nameUsageAlias[0]
+	}
+	if size > 1 {
+		f.usage = nameUsageAlias[1]
+	}
+	if size > 2 {
+		f.alias = nameUsageAlias[2]
+	}
+	return f
+}
+
+func (f *ValueFlag[T]) Name() string {
+	return fmt.Sprintf("-%s", f.name)
+}
+func (f *ValueFlag[T]) Alias() (string, bool) {
+	if len(f.alias) > 0 {
+		return fmt.Sprintf("--%s", f.alias), true
+	}
+	return "", false
+}
+func (f *ValueFlag[T]) Usage() string {
+	return f.usage
+}
+func (f *ValueFlag[T]) Arity() int {
+	return f.arity
+}
+func (f *ValueFlag[T]) TypeName() string {
+	return f.typ
+}
+func (f *ValueFlag[T]) Options() []string {
+	return f.options
+}
+func (f *ValueFlag[T]) Required() bool {
+	return f.required
+}
+func (f *ValueFlag[T]) EnvKey() (string, bool) {
+	return f.env, len(f.env) > 0
+}
+func (f *ValueFlag[T]) DefValue() (any, bool) {
+	return f.def, f.hasDef
+}
+
+func (f *ValueFlag[T]) Default(v T) *ValueFlag[T] {
+	f.def = v
+	f.hasDef = true
+	return f
+}
+
+func (f *ValueFlag[T]) Require() *ValueFlag[T] {
+	f.required = true
+	return f
+}
+
+// 未输入该flag时,从环境变量取值,多个值以逗号分隔
+func (f *ValueFlag[T]) Env(key string) *ValueFlag[T] {
+	f.env = key
+	return f
+}
+
+func (f *ValueFlag[T]) Check(values Args) error {
+	_, err := f.convert(values)
+	return err
+}
+
+func (f *ValueFlag[T]) Convert(values Args) (T, error) {
+	return f.convert(values)
+}
+
+// 兼容FParser
+func (f *ValueFlag[T]) Parse(args Args) (T, bool) {
+	v, err := f.convert(args)
+	return v, err == nil
+}
+
+// 依次取输入值,环境变量,默认值
+func (f *ValueFlag[T]) lookup(fm FlagMap) (values Args, found bool) {
+	if fm != nil {
+		if values, found = fm.HasFlag(f); found {
+			return
+		}
+	}
+	return envValues(f)
+}
+
+func envValues(f FlagDefinition) (Args, bool) {
+	key, ok := f.EnvKey()
+	if !ok {
+		return nil, false
+	}
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil, false
+	}
+	if f.Arity() == 0 || f.Arity() == 1 {
+		return Args{v}, true
+	}
+	return strings.Split(v, ","), true
+}
+
+// 读取flag值: 输入值,环境变量,默认值依次生效;值无效时返回错误信息
+func FlagValue[T any](fm FlagMap, f *ValueFlag[T]) (T, Message) {
+	values, found := f.lookup(fm)
+	if !found {
+		if f.required {
+			return f.def, ErrMessage(CodeUsage, "Flag缺失,需要:%s", f.Name())
+		}
+		return f.def, nil
+	}
+	v, err := f.convert(values)
+	if err != nil {
+		return v, WrapMessage(CodeInvalid, err, "Flag%s值无效", f.Name())
+	}
+	return v, nil
+}
+
+// 检查必填及值的有效性
+func ResolveFlags(flags []Flag, fm FlagMap) Message {
+	for i := range flags {
+		f, ok := flags[i].(FlagDefinition)
+		if !ok {
+			continue
+		}
+		values, found := fm.HasFlag(f)
+		if !found {
+			values, found = envValues(f)
+		}
+		if !found {
+			if f.Required() {
+				return missingFlagMessage(f, flags, fm)
+			}
+			continue
+		}
+		if err := f.Check(values); err != nil {
+			return WrapMessage(CodeInvalid, err, "Flag%s值无效", f.Name())
+		}
+	}
+	return nil
+}
+
+// 输入了相近的未声明flag时,提示可能的拼写错误
+func missingFlagMessage(f Flag, flags []Flag, fm FlagMap) Message {
+	names := []string{f.Name()}
+	if alias, ok := f.Alias(); ok {
+		names = append(names, alias)
+	}
+	msg := Rich(ErrMessage(CodeUsage, "Flag缺失,需要:%s", f.Name()))
+	for _, unknown := range unknownFlags(flags, fm) {
+		if len(Suggest(unknown, names)) > 0 {
+			return msg.WithHint("输入了%s,did you mean `%s`?", unknown, f.Name())
+		}
+	}
+	return msg
+}
+
+func singleValue(values Args) (string, error) {
+	if len(values) == 0 {
+		return "", fmt.Errorf("缺少值")
+	}
+	if len(values) > 1 {
+		return "", fmt.Errorf("只接受一个值,输入:%s", JoinArgs(values))
+	}
+	return values[0], nil
+}
+
+func parseBool(s string) (bool, error) {
+	switch s {
+	case "1", "t", "T", "y", "Y", "yes", "YES", "true", "TRUE", "True":
+		return true, nil
+	case "0", "f", "F", "n", "N", "no", "NO", "false", "FALSE", "False":
+		return false, nil
+	}
+	return false, fmt.Errorf("%s不是有效的bool值", s)
+}
+
+func StringFlag(nameUsageAlias ...string) *ValueFlag[string] {
+	return NewValueFlag("string", 1, singleValue, nameUsageAlias...)
+}
+
+func IntFlag(nameUsageAlias ...string) *ValueFlag[int] {
+	return NewValueFlag("int", 1, func(values Args) (int, error) {
+		s, err := singleValue(values)
+		if err != nil {
+			return 0, err
+		}
+		return strconv.Atoi(s)
+	}, nameUsageAlias...)
+}
+
+func Int64Flag(nameUsageAlias ...string) *ValueFlag[int64] {
+	return NewValueFlag("int64", 1, func(values Args) (int64, error) {
+		s, err := singleValue(values)
+		if err != nil {
+			return 0, err
+		}
+		return strconv.ParseInt(s, 10, 64)
+	}, nameUsageAlias...)
+}
+
+func FloatFlag(nameUsageAlias ...string) *ValueFlag[float64] {
+	return NewValueFlag("float", 1, func(values Args) (float64, error) {
+		s, err := singleValue(values)
+		if err != nil {
+			return 0, err
+		}
+		return strconv.ParseFloat(s, 64)
+	}, nameUsageAlias...)
+}
+
+// 开关flag,出现即为true,也可 -name=false 显式赋值
+func BoolFlag(nameUsageAlias ...string) *ValueFlag[bool] {
+	return NewValueFlag("bool", 0, func(values Args) (bool, error) {
+		if len(values) == 0 {
+			return true, nil
+		}
+		s, err := singleValue(values)
+		if err != nil {
+			return false, err
+		}
+		return parseBool(s)
+	}, nameUsageAlias...)
+}
+
+func DurationFlag(nameUsageAlias ...string) *ValueFlag[time.Duration] {
+	return NewValueFlag("duration", 1, func(values Args) (time.Duration, error) {
+		s, err := singleValue(values)
+		if err != nil {
+			return 0, err
+		}
+		return time.ParseDuration(s)
+	}, nameUsageAlias...)
+}
+
+// 值只能是options之一
+func EnumFlag(options []string, nameUsageAlias ...string) *ValueFlag[string] {
+	typ := strings.Join(options, "|")
+	f := NewValueFlag(typ, 1, func(values Args) (string, error) {
+		s, err := singleValue(values)
+		if err != nil {
+			return "", err
+		}
+		for i := range options {
+			if options[i] == s {
+				return s, nil
+			}
+		}
+		return "", fmt.Errorf("%s不在可选值{%s}中", s, typ)
+	}, nameUsageAlias...)
+	f.options = options
+	return f
+}
+
+// 文件路径,返回清理后的路径
+func PathFlag(nameUsageAlias ...string) *ValueFlag[string] {
+	return NewValueFlag("path", 1, func(values Args) (string, error) {
+		s, err := singleValue(values)
+		if err != nil {
+			return "", err
+		}
+		if len(strings.TrimSpace(s)) == 0 {
+			return "", fmt.Errorf("路径不能为空")
+		}
+		return filepath.Clean(s), nil
+	}, nameUsageAlias...)
+}
+
+func StringsFlag(nameUsageAlias ...string) *ValueFlag[[]string] {
+	return NewValueFlag("strings", ArityVariadic, func(values Args) ([]string, error) {
+		return values, nil
+	}, nameUsageAlias...)
+}
+
+// 多个 key=value 值
+func MapFlag(nameUsageAlias ...string) *ValueFlag[map[string]string] {
+	return NewValueFlag("key=value", ArityVariadic, func(values Args) (map[string]string, error) {
+		m := make(map[string]string, len(values))
+		for i := range values {
+			pos := strings.Index(values[i], "=")
+			if pos < 1 {
+				return nil, fmt.Errorf("%s不是key=value格式", values[i])
+			}
+			m[values[i][0:pos]] = values[i][pos+1:]
+		}
+		return m, nil
+	}, nameUsageAlias...)
+}
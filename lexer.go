@@ -0,0 +1,98 @@
+package gocli
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// flag结束标记,其后的输入都作为参数
+const EndOfFlags = "--"
+
+// 按shell风格切分输入:支持单引号,双引号,反斜杠转义
+// 单引号内不转义;双引号内仅转义 \" \\ \$ \`
+func Tokenize(input string) (Args, Message) {
+	tokens := make(Args, 0, 5)
+	var (
+		w       strings.Builder
+		quote   rune
+		started bool
+		escaped bool
+		qpos    int
+	)
+	for pos, c := range input {
+		if escaped {
+			if quote == '"' && !strings.ContainsRune("\"\\$`", c) {
+				w.WriteRune('\\')
+			}
+			w.WriteRune(c)
+			escaped = false
+			continue
+		}
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				w.WriteRune(c)
+			}
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else if c == '\\' {
+				escaped = true
+			} else {
+				w.WriteRune(c)
+			}
+		case c == '\\':
+			escaped = true
+			started = true
+		case c == '\'' || c == '"':
+			quote = c
+			qpos = pos
+			started = true
+		case unicode.IsSpace(c):
+			if started {
+				tokens = append(tokens, w.String())
+				w.Reset()
+				started = false
+			}
+		default:
+			w.WriteRune(c)
+			started = true
+		}
+	}
+	if quote != 0 {
+		return tokens, ErrMessage(CodeUsage, "引号未闭合:位置%d起的%c", qpos+1, quote)
+	}
+	if escaped {
+		return tokens, ErrMessage(CodeUsage, "输入不能以转义符\\结尾")
+	}
+	if started {
+		tokens = append(tokens, w.String())
+	}
+	return tokens, nil
+}
+
+// 参数包含空白或引号时加引号,保证Tokenize后还原
+func QuoteArg(arg string) string {
+	if len(arg) == 0 {
+		return `""`
+	}
+	if !strings.ContainsAny(arg, " \t\r\n'\"\\") {
+		return arg
+	}
+	if !strings.Contains(arg, "'") {
+		return fmt.Sprintf("'%s'", arg)
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+	return fmt.Sprintf(`"%s"`, r.Replace(arg))
+}
+
+func JoinArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i := range args {
+		quoted[i] = QuoteArg(args[i])
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,51 @@
+package gocli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	cases := []struct {
+		input  string
+		expect Args
+	}{
+		{`copy a.txt dest/`, Args{"copy", "a.txt", "dest/"}},
+		{`open "My Docs/a.txt"`, Args{"open", "My Docs/a.txt"}},
+		{`commit -m 'fix: a "quoted" word'`, Args{"commit", "-m", `fix: a "quoted" word`}},
+		{`echo "say \"hi\" \n"`, Args{"echo", `say "hi" \n`}},
+		{`echo My\ Docs ""`, Args{"echo", "My Docs", ""}},
+		{`echo a"b c"d`, Args{"echo", "ab cd"}},
+		{`  `, Args{}},
+	}
+	for _, c := range cases {
+		tokens, msg := Tokenize(c.input)
+		if msg != nil {
+			t.Fatalf("%s: %s", c.input, msg.Msg())
+		}
+		if !reflect.DeepEqual(tokens, c.expect) {
+			t.Errorf("%s: expect %q, got %q", c.input, c.expect, tokens)
+		}
+		if again, _ := Tokenize(JoinArgs(tokens)); !reflect.DeepEqual(again, tokens) {
+			t.Errorf("%s: JoinArgs not reversible, got %q", c.input, again)
+		}
+	}
+	for _, bad := range []string{`echo "abc`, `echo 'abc`, `echo abc\`} {
+		if _, msg := Tokenize(bad); msg == nil {
+			t.Errorf("%s: expect error", bad)
+		}
+	}
+}
+
+func TestParseLineEndOfFlags(t *testing.T) {
+	args, fmap, msg := ParseLine(`rm -f x -- -notflag "a b"`)
+	if msg != nil {
+		t.Fatal(msg.Msg())
+	}
+	if !reflect.DeepEqual(args, Args{"rm", "-notflag", "a b"}) {
+		t.Errorf("unexpected args %q", args)
+	}
+	if v, _ := fmap.GetStrings("-f"); !reflect.DeepEqual(v, Args{"x"}) {
+		t.Errorf("unexpected flag values %q", v)
+	}
+}
@@ -1,6 +1,18 @@
 package gocli
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+// Message.Code 约定,ExitCode 按此转换为进程退出码
+const (
+	CodeAmbiguous = 300 // 指令不明确
+	CodeUsage     = 400 // 用法错误:输入格式,flag,参数个数
+	CodeNotFound  = 404 // 指令不存在
+	CodeInvalid   = 422 // 参数或flag值校验失败
+	CodePlugin    = 500 // 插件加载或初始化失败
+)
 
 type Message interface {
 	// code < 0 程序应该中断
@@ -73,3 +85,152 @@ func DebugMessage(code int, msg string, msgargs ...any) Message {
 func SuccMessage(code int, msg string, msgargs ...any) Message {
 	return NewMessage(code, msg, LOG_SUCC, msgargs...)
 }
+
+type Detail struct {
+	Key   string
+	Value any
+}
+
+// 可包装error(支持errors.Is/As),附带提示,详情及帮助指令的Message
+type RichMessage struct {
+	message
+	cause   error
+	hint    string
+	details []Detail
+	help    string
+}
+
+// 基于已有Message构建
+func Rich(msg Message) *RichMessage {
+	if rm, ok := msg.(*RichMessage); ok {
+		return rm
+	}
+	rm := &RichMessage{message: message{code: msg.Code(), kind: msg.Kind(), msg: msg.Msg()}}
+	if err, ok := msg.Err(); ok {
+		if _, self := err.(*message); !self {
+			rm.cause = err
+		}
+	}
+	return rm
+}
+
+// 包装err的错误信息
+func WrapMessage(code int, err error, msg string, msgargs ...any) *RichMessage {
+	return Rich(ErrMessage(code, msg, msgargs...)).Wrap(err)
+}
+
+// 复制一份,修改副本不影响原Message
+func (m *RichMessage) clone() *RichMessage {
+	cp := *m
+	cp.details = append([]Detail(nil), m.details...)
+	return &cp
+}
+
+func (m *RichMessage) Wrap(err error) *RichMessage {
+	m.cause = err
+	return m
+}
+
+func (m *RichMessage) WithHint(hint string, args ...any) *RichMessage {
+	if len(args) > 0 {
+		hint = fmt.Sprintf(hint, args...)
+	}
+	m.hint = hint
+	return m
+}
+
+func (m *RichMessage) WithDetail(key string, value any) *RichMessage {
+	m.details = append(m.details, Detail{Key: key, Value: value})
+	return m
+}
+
+// 关联的指令,输出时提示查看该指令的用法
+func (m *RichMessage) WithHelp(commandKey string) *RichMessage {
+	m.help = commandKey
+	return m
+}
+
+func (m *RichMessage) Hint() string {
+	return m.hint
+}
+
+func (m *RichMessage) Details() []Detail {
+	return m.details
+}
+
+func (m *RichMessage) HelpRef() string {
+	return m.help
+}
+
+func (m *RichMessage) Unwrap() error {
+	return m.cause
+}
+
+func (m *RichMessage) Error() string {
+	if m.cause == nil {
+		return m.msg
+	}
+	return fmt.Sprintf("%s: %s", m.msg, m.cause.Error())
+}
+
+func (m *RichMessage) Err() (error, bool) {
+	if m.code < 0 || m.kind > LOG_WARN {
+		return m, true
+	}
+	return nil, false
+}
+
+// 被WithData,RenderMessage包装的Message
+type messageWrapper interface {
+	unwrapMessage() Message
+}
+
+func richOf(msg Message) *RichMessage {
+	for msg != nil {
+		if rm, ok := msg.(*RichMessage); ok {
+			return rm
+		}
+		w, ok := msg.(messageWrapper)
+		if !ok {
+			return nil
+		}
+		msg = w.unwrapMessage()
+	}
+	return nil
+}
+
+// 终端及标准输出统一的Message文本:
+// 信息,原因及详情,提示,帮助指令;按json,yaml渲染的结果只有数据
+func FormatMessage(msg Message) string {
+	if msg == nil {
+		return ""
+	}
+	// Msg取最外层,如按-o渲染后的文本
+	notes := MessageNotes(msg)
+	if len(notes) == 0 || isStructured(msg) {
+		return msg.Msg()
+	}
+	return msg.Msg() + "\n" + notes
+}
+
+// 原因,详情,提示及帮助指令,每项一行;不是RichMessage时为空
+func MessageNotes(msg Message) string {
+	rm := richOf(msg)
+	if rm == nil {
+		return ""
+	}
+	lines := make([]string, 0, len(rm.details)+3)
+	if rm.cause != nil {
+		lines = append(lines, fmt.Sprintf("  原因: %s", rm.cause.Error()))
+	}
+	for _, d := range rm.details {
+		lines = append(lines, fmt.Sprintf("  %s: %v", d.Key, d.Value))
+	}
+	if len(rm.hint) > 0 {
+		lines = append(lines, fmt.Sprintf("提示: %s", rm.hint))
+	}
+	if len(rm.help) > 0 {
+		lines = append(lines, fmt.Sprintf("使用:\"command help %s\"获取更多信息", rm.help))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,70 @@
+package gocli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRichMessage(t *testing.T) {
+	_, err := os.Open("not_exist.gocli")
+	msg := WrapMessage(0, err, "打开脚本失败").WithDetail("文件", "not_exist.gocli").WithHint("检查路径").WithHelp("source")
+	e, failed := msg.Err()
+	if !failed || !errors.Is(e, fs.ErrNotExist) {
+		t.Errorf("expect wrapped ErrNotExist, got %v", e)
+	}
+	var perr *fs.PathError
+	if !errors.As(e, &perr) || perr.Path != "not_exist.gocli" {
+		t.Errorf("expect *fs.PathError, got %v", e)
+	}
+	expect := "打开脚本失败\n  原因: " + err.Error() + "\n  文件: not_exist.gocli\n提示: 检查路径\n使用:\"command help source\"获取更多信息"
+	if text := FormatMessage(msg); text != expect {
+		t.Errorf("expect %q, got %q", expect, text)
+	}
+	if text := FormatMessage(InfoMessage(0, "done")); text != "done" {
+		t.Errorf("plain message should render as Msg(), got %q", text)
+	}
+	if rm := Rich(WarnMessage(CodeNotFound, "command not found")); rm.Code() != CodeNotFound || rm.Kind() != LOG_WARN || rm.Unwrap() != nil {
+		t.Errorf("Rich should keep code and kind, got %+v", rm)
+	}
+	if ExitCode(msg.WithHelp("")) != ExitFailure {
+		t.Error("rich error should exit with failure")
+	}
+}
+
+func TestRichMessageWithData(t *testing.T) {
+	msg := WithData(Rich(InfoMessage(0, "已生成")).WithHint("复制公钥").WithDetail("id", "ab12"), []string{"ab12"})
+	if text := FormatMessage(msg); text != "已生成\n  id: ab12\n提示: 复制公钥" {
+		t.Errorf("hint and details should survive WithData, got %q", text)
+	}
+	// 结构化输出只有数据,提示写到stderr
+	rendered := RenderMessage(msg, OutputJson)
+	var ids []string
+	if err := json.Unmarshal([]byte(FormatMessage(rendered)), &ids); err != nil || len(ids) != 1 {
+		t.Errorf("json output should stay parseable, got %q: %v", FormatMessage(rendered), err)
+	}
+	var stdout, stderr bytes.Buffer
+	printResult(rendered, &stdout, &stderr)
+	if err := json.Unmarshal(stdout.Bytes(), &ids); err != nil {
+		t.Errorf("stdout should be json, got %q: %v", stdout.String(), err)
+	}
+	if stderr.String() != "  id: ab12\n提示: 复制公钥\n" {
+		t.Errorf("notes should go to stderr, got %q", stderr.String())
+	}
+	if text := FormatMessage(RenderMessage(msg, OutputTable)); !strings.HasSuffix(text, "提示: 复制公钥") {
+		t.Errorf("table output should keep notes, got %q", text)
+	}
+}
+
+func TestHelpRef(t *testing.T) {
+	r := testRegistry(gogenCore)
+	_, _, _, msg := resolveInput(r, Args{"genplugin", "a.go", "-nme", "demo"})
+	rm, ok := msg.(*RichMessage)
+	if !ok || rm.HelpRef() != "genplugin" || len(rm.Hint()) == 0 {
+		t.Errorf("expect help reference and hint, got %+v", msg)
+	}
+}
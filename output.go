@@ -0,0 +1,376 @@
+package gocli
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// -o 输出格式
+const (
+	OutputText  = "text"
+	OutputJson  = "json"
+	OutputYaml  = "yaml"
+	OutputTable = "table"
+)
+
+// 携带结构化数据(对象,map,列表)的Message,-o json|yaml|table 时按数据输出,
+// text 输出 Msg(),为空时按表格输出
+type DataMessage interface {
+	Message
+	Data() any
+}
+
+func WithData(msg Message, data any) DataMessage {
+	return &dataMessage{Message: msg, data: data}
+}
+
+type dataMessage struct {
+	Message
+	data any
+}
+
+func (m *dataMessage) Data() any {
+	return m.data
+}
+
+func (m *dataMessage) unwrapMessage() Message {
+	return m.Message
+}
+
+// 按format渲染后的Message,Data()仍可获取原数据
+type renderedMessage struct {
+	DataMessage
+	text   string
+	format string
+}
+
+func (m *renderedMessage) Msg() string {
+	return m.text
+}
+
+func (m *renderedMessage) unwrapMessage() Message {
+	return m.DataMessage
+}
+
+// 按format输出DataMessage,其他Message原样返回
+func RenderMessage(msg Message, format string) Message {
+	dm, ok := msg.(DataMessage)
+	if !ok {
+		return msg
+	}
+	if (len(format) == 0 || format == OutputText) && len(dm.Msg()) > 0 {
+		return msg
+	}
+	text, err := Render(dm.Data(), format)
+	if err != nil {
+		return ErrMessage(0, "输出%s格式失败:%s", format, err.Error())
+	}
+	return &renderedMessage{DataMessage: dm, text: text, format: format}
+}
+
+// 按json,yaml渲染的Message,文本须保持可解析
+func isStructured(msg Message) bool {
+	rm, ok := msg.(*renderedMessage)
+	return ok && (rm.format == OutputJson || rm.format == OutputYaml)
+}
+
+// 按format(json|yaml|table|text)输出数据,text同table
+func Render(data any, format string) (string, error) {
+	v := normalize(reflect.ValueOf(data))
+	switch format {
+	case OutputJson:
+		b, err := marshalJson(v, "  ")
+		return string(b), err
+	case OutputYaml:
+		var w strings.Builder
+		writeYaml(&w, v, 0)
+		return strings.TrimRight(w.String(), "\n"), nil
+	case OutputTable, OutputText, "":
+		return renderTable(v), nil
+	}
+	return "", fmt.Errorf("不支持的格式:%s", format)
+}
+
+// 保持字段顺序的对象
+type field struct {
+	key   string
+	value any
+}
+
+type object []field
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := marshalJson(f.value, "")
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func marshalJson(v any, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+var textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// 转换为 object,[]any 或基础类型;结构体按json tag命名,map按key排序
+func normalize(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().Implements(textMarshaler) && (v.Kind() != reflect.Pointer || !v.IsNil()) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err == nil {
+			return string(text)
+		}
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return normalize(v.Elem())
+	case reflect.Struct:
+		o := make(object, 0, v.NumField())
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			name, omitempty := sf.Name, false
+			if tag, ok := sf.Tag.Lookup("json"); ok {
+				if tag == "-" {
+					continue
+				}
+				parts := strings.Split(tag, ",")
+				if len(parts[0]) > 0 {
+					name = parts[0]
+				}
+				for _, opt := range parts[1:] {
+					omitempty = omitempty || opt == "omitempty"
+				}
+			}
+			if omitempty && v.Field(i).IsZero() {
+				continue
+			}
+			o = append(o, field{name, normalize(v.Field(i))})
+		}
+		return o
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		o := make(object, 0, len(keys))
+		for _, k := range keys {
+			o = append(o, field{fmt.Sprint(k.Interface()), normalize(v.MapIndex(k))})
+		}
+		return o
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes())
+		}
+		list := make([]any, v.Len())
+		for i := range list {
+			list[i] = normalize(v.Index(i))
+		}
+		return list
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	return v.Interface()
+}
+
+func writeYaml(w *strings.Builder, v any, indent int) {
+	pad := strings.Repeat(" ", indent)
+	switch value := v.(type) {
+	case object:
+		if len(value) == 0 {
+			w.WriteString(pad + "{}\n")
+			return
+		}
+		for _, f := range value {
+			w.WriteString(pad + yamlScalar(f.key) + ":")
+			writeYamlValue(w, f.value, indent)
+		}
+	case []any:
+		if len(value) == 0 {
+			w.WriteString(pad + "[]\n")
+			return
+		}
+		for _, item := range value {
+			if isNested(item) {
+				// 对象或列表的第一行紧跟在 "- " 之后
+				var sub strings.Builder
+				writeYaml(&sub, item, indent+2)
+				w.WriteString(pad + "- " + sub.String()[indent+2:])
+				continue
+			}
+			w.WriteString(pad + "-")
+			writeYamlValue(w, item, indent)
+		}
+	default:
+		w.WriteString(pad + yamlScalar(value) + "\n")
+	}
+}
+
+// 非空的对象或列表
+func isNested(v any) bool {
+	switch value := v.(type) {
+	case object:
+		return len(value) > 0
+	case []any:
+		return len(value) > 0
+	}
+	return false
+}
+
+// 写在 "key:" 或 "-" 之后的值
+func writeYamlValue(w *strings.Builder, v any, indent int) {
+	switch value := v.(type) {
+	case object:
+		if len(value) == 0 {
+			w.WriteString(" {}\n")
+			return
+		}
+	case []any:
+		if len(value) == 0 {
+			w.WriteString(" []\n")
+			return
+		}
+	default:
+		w.WriteString(" " + yamlScalar(value) + "\n")
+		return
+	}
+	w.WriteString("\n")
+	writeYaml(w, v, indent+2)
+}
+
+func yamlScalar(v any) string {
+	switch value := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		if needQuote(value) {
+			return strconv.Quote(value)
+		}
+		return value
+	}
+	return fmt.Sprint(v)
+}
+
+func needQuote(s string) bool {
+	if len(s) == 0 || strings.TrimSpace(s) != s || strings.ContainsAny(s, ":#\n\t\"'{}[],&*!|>%@`") || strings.HasPrefix(s, "-") {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "null", "~", "true", "false", "yes", "no", "on", "off":
+		return true
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// 对象列表按列输出,对象按 KEY VALUE 输出,值列表按单列输出
+func renderTable(v any) string {
+	var (
+		headers []string
+		rows    [][]string
+	)
+	switch value := v.(type) {
+	case object:
+		headers = []string{"KEY", "VALUE"}
+		for _, f := range value {
+			rows = append(rows, []string{f.key, cellText(f.value)})
+		}
+	case []any:
+		columns := make(map[string]int, 5)
+		for _, item := range value {
+			if o, ok := item.(object); ok {
+				for _, f := range o {
+					if _, exist := columns[f.key]; !exist {
+						columns[f.key] = len(headers)
+						headers = append(headers, f.key)
+					}
+				}
+			}
+		}
+		if len(headers) == 0 {
+			headers = []string{"VALUE"}
+		}
+		for _, item := range value {
+			row := make([]string, len(headers))
+			if o, ok := item.(object); ok {
+				for _, f := range o {
+					row[columns[f.key]] = cellText(f.value)
+				}
+			} else {
+				row[0] = cellText(item)
+			}
+			rows = append(rows, row)
+		}
+		for i := range headers {
+			headers[i] = strings.ToUpper(headers[i])
+		}
+	default:
+		return cellText(v)
+	}
+	widths := make([]int, len(headers))
+	for _, row := range append([][]string{headers}, rows...) {
+		for i := range row {
+			if w := runewidth.StringWidth(row[i]); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+	var w strings.Builder
+	for _, row := range append([][]string{headers}, rows...) {
+		var line strings.Builder
+		for i := range row {
+			line.WriteString(runewidth.FillRight(row[i], widths[i]+2))
+		}
+		w.WriteString(strings.TrimRight(line.String(), " "))
+		w.WriteString("\n")
+	}
+	return strings.TrimRight(w.String(), "\n")
+}
+
+func cellText(v any) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case object, []any:
+		b, _ := marshalJson(value, "")
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,106 @@
+package gocli
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type outputItem struct {
+	Name    string            `json:"name"`
+	Size    int               `json:"size"`
+	Timeout time.Duration     `json:"timeout"`
+	Tags    []string          `json:"tags,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	secret  string
+}
+
+func TestRender(t *testing.T) {
+	items := []outputItem{
+		{Name: "db", Size: 3, Timeout: time.Second, Tags: []string{"a", "b"}, secret: "x"},
+		{Name: "no", Size: 12, Labels: map[string]string{"z": "1", "a": "yes"}},
+	}
+	cases := map[string]string{
+		OutputJson: `[
+  {
+    "name": "db",
+    "size": 3,
+    "timeout": "1s",
+    "tags": [
+      "a",
+      "b"
+    ]
+  },
+  {
+    "name": "no",
+    "size": 12,
+    "timeout": "0s",
+    "labels": {
+      "a": "yes",
+      "z": "1"
+    }
+  }
+]`,
+		OutputYaml: `- name: db
+  size: 3
+  timeout: 1s
+  tags:
+    - a
+    - b
+- name: "no"
+  size: 12
+  timeout: 0s
+  labels:
+    a: "yes"
+    z: "1"`,
+		OutputTable: `NAME  SIZE  TIMEOUT  TAGS       LABELS
+db    3     1s       ["a","b"]
+no    12    0s                  {"a":"yes","z":"1"}`,
+	}
+	for format, expect := range cases {
+		got, err := Render(items, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != expect {
+			t.Errorf("%s: expect\n%s\ngot\n%s", format, expect, got)
+		}
+	}
+	if got, _ := Render(map[string]int{"b": 2, "a": 1}, OutputTable); got != "KEY  VALUE\na    1\nb    2" {
+		t.Errorf("unexpected map table:\n%s", got)
+	}
+	if _, err := Render(items, "xml"); err == nil {
+		t.Error("xml is not supported")
+	}
+}
+
+func TestOutputFlag(t *testing.T) {
+	ctx := testContext(t, gogenCore)
+	ctx.SetValue(history_list, &historyItems{{"show", "plugins"}, {"ws"}})
+	cases := map[string]string{
+		"show history":          "1. show plugins\n2. ws\n",
+		"show history -o json":  "[\n  \"show plugins\",\n  \"ws\"\n]",
+		"show history -o yaml":  "- show plugins\n- ws",
+		"show plugins -o table": "KEY      VALUE\nplugins  [{\"name\":\"gogen_core\",\"version\":\"v0.0.1\",\"sha256\":\"N/A\",\"file\":\"N/A\"}]",
+	}
+	for input, expect := range cases {
+		msg, ok := runScriptLine(ctx, ctx.registry(), input)
+		if !ok || msg.Msg() != expect {
+			t.Errorf("%s: expect\n%q\ngot\n%q", input, expect, msg.Msg())
+		}
+	}
+	// 加载报告随插件列表一起输出
+	ctx.SetValue(load_report, &LoadReport{
+		Rejected: []*LoadIssue{{File: "bad.so", Stage: LoadVerify, Message: "未签名"}},
+		Warnings: []*LoadIssue{{File: "old.so", Stage: LoadVerify, Message: "摘要不符"}},
+	})
+	msg, _ := runScriptLine(ctx, ctx.registry(), "show plugins -o json")
+	for _, expect := range []string{`"plugins": [`, `"rejected": [`, `"file": "bad.so"`, `"warnings": [`, `"reason": "摘要不符"`} {
+		if !strings.Contains(msg.Msg(), expect) {
+			t.Errorf("show plugins json should contain %s:\n%s", expect, msg.Msg())
+		}
+	}
+	if _, ok := runScriptLine(ctx, ctx.registry(), "ws -o xml"); ok {
+		t.Error("-o xml should be rejected")
+	}
+}
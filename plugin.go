@@ -1,10 +1,9 @@
 package gocli
 
 import (
-	"crypto/md5"
-	"encoding/hex"
+	"crypto/sha256"
+	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"plugin"
@@ -63,6 +62,85 @@ type Plugin interface {
 	Helper() HelperFunc
 }
 
+// 可选:程序退出前按安装的逆序调用,ctx带有截止时间(-shutdown-timeout),
+// 用于写入文件,关闭数据库连接,停止后台goroutine等
+type ShutdownPlugin interface {
+	Plugin
+	Shutdown(ctx Context) error
+}
+
+// 插件可通过 CoreRequirement 声明要求的最低核心版本
+const CoreVersion = core_version
+
+// 可选:插件要求的gocli核心最低版本,如 "1.2.0",
+// 核心版本需不低于该版本,且1.0之后major相同;
+// 不满足时 .so 插件不加载,内置插件启动失败
+type CoreRequirement interface {
+	Plugin
+	MinCoreVersion() string
+}
+
+// 插件版本需符合语义化版本
+func checkVersion(p Plugin) error {
+	if _, err := ParseVersion(p.Version()); err != nil {
+		return fmt.Errorf("插件%s: %w", p.Name(), err)
+	}
+	return nil
+}
+
+// 当前核心版本是否满足插件的要求
+func checkCore(p Plugin) error {
+	return checkCoreVersion(p, CoreVersion)
+}
+
+func checkCoreVersion(p Plugin, coreVersion string) error {
+	if lp, ok := p.(*LoadedPlugin); ok {
+		p = lp.Plugin
+	}
+	cr, ok := p.(CoreRequirement)
+	if !ok || len(cr.MinCoreVersion()) == 0 {
+		return nil
+	}
+	min, err := ParseVersion(cr.MinCoreVersion())
+	if err != nil {
+		return fmt.Errorf("插件%s要求的核心版本: %w", p.Name(), err)
+	}
+	core, _ := ParseVersion(coreVersion)
+	if core.Compare(min) < 0 {
+		return fmt.Errorf("插件%s要求核心版本>=%s,当前%s", p.Name(), min, core)
+	}
+	// 1.0之后major不同即不兼容;0.x期间只要求不低于min
+	if min.Major >= 1 && core.Major != min.Major {
+		return fmt.Errorf("插件%s要求核心版本%d.x(>=%s),当前%s", p.Name(), min.Major, min, core)
+	}
+	return nil
+}
+
+// 可选:声明依赖的插件及版本约束,如 {"db_plugin": ">=1.2.0"},
+// 被依赖的插件先于该插件Setup及BeforeRun
+type DependentPlugin interface {
+	Plugin
+	Requires() map[string]string
+}
+
+func requiresOf(p Plugin) map[string]string {
+	if lp, ok := p.(*LoadedPlugin); ok {
+		p = lp.Plugin
+	}
+	if dp, ok := p.(DependentPlugin); ok {
+		return dp.Requires()
+	}
+	return nil
+}
+
+func shutdownOf(p Plugin) (ShutdownPlugin, bool) {
+	if lp, ok := p.(*LoadedPlugin); ok {
+		p = lp.Plugin
+	}
+	sp, ok := p.(ShutdownPlugin)
+	return sp, ok
+}
+
 type LifeHook = func(ctx Context) error
 
 type GeneralPlugin struct {
@@ -71,6 +149,9 @@ type GeneralPlugin struct {
 	Ver      string
 	Init     LifeHook
 	PreRun   LifeHook
+	Stop     LifeHook
+	Deps     map[string]string
+	MinCore  string
 	Commands []Command
 	Help     HelperFunc
 }
@@ -90,7 +171,7 @@ func (gp *GeneralPlugin) Usage() string {
 }
 func (gp *GeneralPlugin) Version() string {
 	if len(gp.Ver) == 0 {
-		return "unkown"
+		return "0.0.0"
 	}
 	return gp.Ver
 }
@@ -106,6 +187,18 @@ func (gp *GeneralPlugin) Setup(ctx Context) error {
 	}
 	return gp.Init(ctx)
 }
+func (gp *GeneralPlugin) MinCoreVersion() string {
+	return gp.MinCore
+}
+func (gp *GeneralPlugin) Requires() map[string]string {
+	return gp.Deps
+}
+func (gp *GeneralPlugin) Shutdown(ctx Context) error {
+	if gp.Stop == nil {
+		return nil
+	}
+	return gp.Stop(ctx)
+}
 func (gp *GeneralPlugin) Registry() []Command {
 	if gp.Commands == nil {
 		return make([]Command, 0)
@@ -116,68 +209,161 @@ func (gp *GeneralPlugin) Registry() []Command {
 const ExportPlugin = "Plugin"
 
 type LoadedPlugin struct {
-	File     string
-	Digest   string
+	File string
+	// 文件的sha256摘要
+	Digest string
+	// 签名的公钥名称
+	Signer   string
 	Verified bool
+	// 未通过签名校验的原因
+	SignErr error
+	sign    *signature
 	Plugin
 }
 
-func LoadPlugin(dirs []string, console Log) ([]*LoadedPlugin, int) {
+// 插件加载的阶段,用于说明插件在哪一步被拒绝
+type LoadStage string
+
+const (
+	LoadScan    LoadStage = "scan"
+	LoadRead    LoadStage = "read"
+	LoadVerify  LoadStage = "verify"
+	LoadOpen    LoadStage = "open"
+	LoadLookup  LoadStage = "lookup"
+	LoadVersion LoadStage = "version"
+)
+
+// 被拒绝或签名异常的插件
+type LoadIssue struct {
+	File   string    `json:"file"`
+	Stage  LoadStage `json:"stage"`
+	Reason error     `json:"-"`
+	// Reason的文本,便于输出
+	Message string `json:"reason"`
+	// 已计算出摘要时为文件的sha256
+	Digest string `json:"sha256,omitempty"`
+}
+
+func (r LoadIssue) Error() string {
+	return fmt.Sprintf("%s(%s): %s", filepath.Base(r.File), r.Stage, r.Message)
+}
+
+func (r LoadIssue) Unwrap() error {
+	return r.Reason
+}
+
+// 插件加载结果
+type LoadReport struct {
+	// 扫描到的 .so 文件数
+	Found    int             `json:"found"`
+	Loaded   []*LoadedPlugin `json:"-"`
+	Rejected []*LoadIssue    `json:"rejected"`
+	// 未使用-check时,签名校验失败(如签名后文件被修改)但仍尝试加载的插件
+	Warnings []*LoadIssue `json:"warnings,omitempty"`
+}
+
+func (report *LoadReport) reject(file string, stage LoadStage, err error) *LoadIssue {
+	r := &LoadIssue{File: file, Stage: stage, Reason: err, Message: err.Error()}
+	report.Rejected = append(report.Rejected, r)
+	return r
+}
+
+func (report *LoadReport) warn(file string, stage LoadStage, err error) *LoadIssue {
+	r := &LoadIssue{File: file, Stage: stage, Reason: err, Message: err.Error()}
+	report.Warnings = append(report.Warnings, r)
+	return r
+}
+
+// 加载dirs下的 *.so 插件:读取文件并计算摘要,用store中的公钥校验签名后,
+// 从校验过的副本打开,文件的内容在校验与加载之间不会被替换;
+// verify为true时,未签名或公钥不可信的插件不会被打开(不执行插件的init)
+func LoadPlugin(dirs []string, store *TrustStore, verify bool, console Log) *LoadReport {
+	report := &LoadReport{}
 	pluginFiles := make([]string, 0, 5)
 	for i := range dirs {
 		dir := dirs[i]
 		p, err := os.Stat(dir)
 		if err != nil || !p.IsDir() {
-			console.Warn("[load plugin] scan dir:%s ,invalid dir", dir)
+			report.reject(dir, LoadScan, fmt.Errorf("无效的插件目录"))
 			continue
 		}
 		files, err := filepath.Glob(fmt.Sprintf("%s/*.so", dir))
 		if err != nil {
-			console.Warn("[load plugin] scan dir:%s ,invalid files", dir)
+			report.reject(dir, LoadScan, err)
 			continue
 		}
 		if len(files) > 0 {
 			pluginFiles = append(pluginFiles, files...)
 		}
 	}
-	num := len(pluginFiles)
-	console.Info("[load plugin] found %d 个插件", num)
-	verified := make([]*LoadedPlugin, 0, num)
+	report.Found = len(pluginFiles)
+	console.Info("[load plugin] found %d 个插件", report.Found)
 	for i := range pluginFiles {
 		pf := pluginFiles[i]
 		pp, _ := filepath.Abs(pf)
 		console.Info("preload plugin %s", pf)
-		pn := filepath.Base(pp)
-		p, err := plugin.Open(pp)
-		if err != nil {
-			console.Err("[load plugin] %s:open failed,%s", pn, err.Error())
-			continue
-		}
-		plugin, err := p.Lookup(ExportPlugin)
+		bts, err := os.ReadFile(pp)
 		if err != nil {
-			console.Err("[load plugin] %s:lookup failed", pn)
+			report.reject(pf, LoadRead, err)
 			continue
 		}
-		v, ok := plugin.(Plugin)
-		if !ok {
-			console.Err("[load plugin] %s:covert failed", pn)
+		digest := sha256.Sum256(bts)
+		sign, serr := verifyDigest(pp, digest[:], store)
+		if serr != nil && verify {
+			report.reject(pf, LoadVerify, serr).Digest = sign.digest
 			continue
 		}
-		console.Succ("[load plugin] Name:%s ,Version:%s", v.Name(), v.Version())
-		var digest string
-		{
-			f, _ := os.Open(pp)
-			hash := md5.New()
-			io.Copy(hash, f)
-			digest = hex.EncodeToString(hash.Sum(nil))
+		// 有签名但校验失败(如签名后文件被修改),与未签名区分
+		if serr != nil && !errors.Is(serr, ErrUnsigned) {
+			report.warn(pf, LoadVerify, serr).Digest = sign.digest
 		}
-		checkSign := false
-		bts, err := os.ReadFile(fmt.Sprintf("%s.md5", pp))
+		v, stage, err := openVerified(filepath.Base(pp), bts)
 		if err == nil {
-			fileMd5 := string(bts)
-			checkSign = fileMd5 == digest
+			if err = checkVersion(v); err == nil {
+				err = checkCore(v)
+			}
+			stage = LoadVersion
+		}
+		if err != nil {
+			report.reject(pf, stage, err).Digest = sign.digest
+			continue
 		}
-		verified = append(verified, &LoadedPlugin{Plugin: v, Digest: digest, Verified: checkSign, File: pf})
+		console.Succ("[load plugin] Name:%s ,Version:%s", v.Name(), v.Version())
+		report.Loaded = append(report.Loaded, &LoadedPlugin{
+			Plugin:   v,
+			File:     pf,
+			Digest:   sign.digest,
+			Signer:   sign.signer,
+			Verified: sign.verified,
+			SignErr:  serr,
+			sign:     sign,
+		})
+	}
+	return report
+}
+
+// 把校验过的内容写入仅当前用户可访问的临时目录后打开,打开后即删除
+func openVerified(name string, bts []byte) (Plugin, LoadStage, error) {
+	dir, err := os.MkdirTemp("", "gocli-plugin-")
+	if err != nil {
+		return nil, LoadOpen, err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, name)
+	if err := os.WriteFile(file, bts, 0500); err != nil {
+		return nil, LoadOpen, err
+	}
+	p, err := plugin.Open(file)
+	if err != nil {
+		return nil, LoadOpen, err
+	}
+	sym, err := p.Lookup(ExportPlugin)
+	if err != nil {
+		return nil, LoadLookup, err
+	}
+	v, ok := sym.(Plugin)
+	if !ok {
+		return nil, LoadLookup, fmt.Errorf("%s未实现Plugin接口", ExportPlugin)
 	}
-	return verified, len(verified)
+	return v, "", nil
 }
@@ -2,6 +2,8 @@ package gocli
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 )
@@ -20,73 +22,129 @@ const (
 
 type historyItems = [][]string
 
-var (
-	pname    = NewFlag("nm")
-	pversion = NewFlag("ver")
-	pexport  = NewFlag("typ")
-	pusage   = NewFlag("rmk")
-)
+type workspaceInfo struct {
+	WorkDir string `json:"workdir"`
+	AbsPath string `json:"abs_path,omitempty"`
+}
+
+type pluginInfo struct {
+	Name    string           `json:"name"`
+	Version string           `json:"version"`
+	Sha256  string           `json:"sha256"`
+	Signer  string           `json:"signer,omitempty"`
+	File    string           `json:"file"`
+	Depends []dependencyInfo `json:"depends,omitempty"`
+}
+
+// show plugins 的数据:已加载的插件及加载报告中的异常
+type pluginList struct {
+	Plugins  []pluginInfo `json:"plugins"`
+	Rejected []*LoadIssue `json:"rejected,omitempty"`
+	Warnings []*LoadIssue `json:"warnings,omitempty"`
+}
+
+type dependencyInfo struct {
+	Name       string `json:"name"`
+	Constraint string `json:"constraint"`
+	Version    string `json:"version"`
+}
+
+type genpluginOptions struct {
+	Name    string `flag:"nm,alias=name" usage:"插件名称" required:"true"`
+	Version string `flag:"ver,alias=version" usage:"插件版本" default:"v0.0.1"`
+	Usage   string `flag:"rmk,alias=remark" usage:"插件描述" default:"something todo."`
+	Export  string `flag:"typ,alias=type" usage:"对外暴露的数据类型"`
+}
+
+type pluginSignOptions struct {
+	Key string `flag:"key" usage:"签名的私钥文件" required:"true"`
+}
+
+type pluginKeygenOptions struct {
+	Dir string `flag:"dir" usage:"密钥对写入的目录" default:"."`
+}
+
+type completionOptions struct {
+	Prog string `flag:"prog" usage:"补全的程序名称,默认为当前程序文件名"`
+}
 
 var (
-	_genplugin = NewCommand("genplugin", "生成一个插件模板文档 eg: genplugin plugin/demo/plugin.go -nm demo_plugin -ver ", BuildRun(
-		func(ctx Context, args []string, flagmap FlagMap) Message {
+	_completion = WithCompleter(NewBindCommand("completion", "输出shell补全脚本 eg: source <(app completion bash)",
+		func(ctx Context, args []string, opts *completionOptions) Message {
+			prog := opts.Prog
+			if len(prog) == 0 {
+				prog = filepath.Base(os.Args[0])
+			}
+			script, err := ShellCompletion(args[0], prog)
+			if err != nil {
+				return ErrMessage(CodeInvalid, err.Error())
+			}
+			return InfoMessage(0, script)
+		},
+		InputRules(ExactlyLength(1, ErrMessage(CodeUsage, "需要指定shell:%s", strings.Join(shells, "|")))),
+	), func(ctx Context, args Args, current string) []string {
+		if len(args) > 0 {
+			return nil
+		}
+		return withPrefix(shells, current)
+	})
+	_complete = WithHidden(NewCommand(completeCommandKey, "shell补全回调 eg: __complete -- show h", func(ctx Context, args []string, flagmap FlagMap) Message {
+		return InfoMessage(0, strings.Join(shellComplete(ctx, args), "\n"))
+	}))
+	_source = WithCompleter(NewCommand("source", "执行脚本文件中的指令 eg: source setup.gocli -continue-on-error", BuildRun(func(ctx Context, args []string, flagmap FlagMap) Message {
+		cont, _ := FlagValue(flagmap, ContinueOnErr)
+		return RunScriptFile(ctx, args[0], cont)
+	}, InputRules(ExactlyLength(1, nil)))), CompletePath)
+	_genplugin = WithCompleter(NewBindCommand("genplugin", "生成一个插件模板文档 eg: genplugin plugin/demo/plugin.go -nm demo_plugin -ver ",
+		func(ctx Context, args []string, opts *genpluginOptions) Message {
 			file := args[0]
-			name, _ := flagmap.GetString(pname.Name())
-			version, _ := flagmap.GetString(pversion.Name())
-			usage, _ := flagmap.GetString(pusage.Name())
-			export, _ := flagmap.GetString(pexport.Name())
 			bean := &PluginBean{
-				Name:       name,
-				Version:    version,
-				Usage:      usage,
-				ExportType: export,
+				Name:       opts.Name,
+				Version:    opts.Version,
+				Usage:      opts.Usage,
+				ExportType: opts.Export,
 			}
 			err := RenderPluginFile(bean, file)
 			if err != nil {
-				return ErrMessage(0, err.Error())
+				return WrapMessage(0, err, "生成pluginfile失败").WithDetail("文件", file)
 			}
 			return InfoMessage(0, "生成pluginfile成功:%s", file)
 		},
-		InputRules(
-			ExactlyLength(1, nil),
-			MustFlagged(
-				&FlagExpected{Flag: pname, Required: true, Validator: ExactlyLength(1, nil)},
-				&FlagExpected{Flag: pversion, DefVal: "v0.0.1"},
-				&FlagExpected{Flag: pusage, DefVal: "something todo."},
-			),
-		),
-	))
-	_quit = NewCommand("quit", "退出程序", func(ctx Context, args []string, flagmap FlagMap) Message {
+		InputRules(ExactlyLength(1, nil)),
+	), CompletePath)
+	_quit = WithAliases(NewCommand("quit", "退出程序", func(ctx Context, args []string, flagmap FlagMap) Message {
 		ctx.Interupt()
 		return InteruptMessage("退出程序")
-	})
+	}), "exit")
 	_workspace = NewCommand("ws", "显示当前工作空间状态相关信息", BuildRun(func(ctx Context, args []string, flagmap FlagMap) Message {
 		var w strings.Builder
-		path := ctx.WorkDir()
-		w.WriteString(fmt.Sprintf("WorkDir: %s", path))
-		return InfoMessage(0, w.String())
+		ws := workspaceInfo{WorkDir: ctx.WorkDir()}
+		if abs, err := filepath.Abs(ws.WorkDir); err == nil {
+			ws.AbsPath = abs
+		}
+		w.WriteString(fmt.Sprintf("WorkDir: %s", ws.WorkDir))
+		return WithData(InfoMessage(0, w.String()), ws)
 	}, InputRules(EmptyArgs())))
-	_history = NewCommand("show history", "显示历史", func(ctx Context, args []string, flagmap FlagMap) Message {
+	_history = WithAliases(NewCommand("show history", "显示历史", func(ctx Context, args []string, flagmap FlagMap) Message {
 		var w strings.Builder
+		lines := make([]string, 0, 10)
 		v := ctx.Value(history_list)
 		if v != nil {
 			items, ok := v.(*historyItems)
 			if !ok {
-				w.WriteString("history type error")
-			} else {
-				histories := *items
-				for i := range histories {
-					w.WriteString(fmt.Sprintf("%d. ", i+1))
-					w.WriteString(strings.Join(histories[i], " "))
-					w.WriteString("\n")
-				}
+				return ErrMessage(0, "history type error")
+			}
+			histories := *items
+			for i := range histories {
+				lines = append(lines, JoinArgs(histories[i]))
+				w.WriteString(fmt.Sprintf("%d. %s\n", i+1, lines[i]))
 			}
 		}
 		if w.Len() == 0 {
 			w.WriteString("空")
 		}
-		return InfoMessage(0, w.String())
-	})
+		return WithData(InfoMessage(0, w.String()), lines)
+	}), "history")
 	_plugins = NewCommand("show plugins", "查看加载的插件列表", func(ctx Context, args []string, flagmap FlagMap) Message {
 		info := ctx.RegisteredPlugins()
 		var w strings.Builder
@@ -100,34 +158,112 @@ var (
 			}
 		}
 		sort.Strings(keys)
+		list := pluginList{Plugins: make([]pluginInfo, 0, len(keys))}
 		for i := range keys {
 			bundle := info[keys[i]]
+			row := pluginInfo{
+				Name:    keys[i],
+				Version: bundle.Version(),
+				Sha256:  bundle.Digest(),
+				Signer:  bundle.Signer(),
+				File:    bundle.File(),
+			}
+			signer := row.Signer
+			if len(signer) == 0 {
+				signer = "N/A"
+			}
 			w.WriteString(fmt.Sprintf(
-				"%s%s %s|md5(%s)|file(%s)\n",
+				"%s%s %s|sha256(%s)|signer(%s)|file(%s)\n",
 				keys[i],
 				strings.Repeat(" ", max-len(keys[i])),
 				bundle.Version(),
-				bundle.Md5(),
+				row.Sha256,
+				signer,
 				bundle.File(),
 			))
+			// 依赖关系: └─ db_plugin >=1.2.0 (1.3.0)
+			requires := bundle.Requires()
+			for _, dep := range bundle.Dependencies() {
+				d := dependencyInfo{Name: dep.Name(), Constraint: requires[dep.Name()], Version: dep.Version()}
+				if len(d.Constraint) == 0 {
+					d.Constraint = "*"
+				}
+				row.Depends = append(row.Depends, d)
+				w.WriteString(fmt.Sprintf("%s└─ %s %s (%s)\n", indent, d.Name, d.Constraint, d.Version))
+			}
+			list.Plugins = append(list.Plugins, row)
 		}
-		return InfoMessage(0, w.String())
+		// 未通过校验或加载失败的插件,及签名异常但已加载的插件
+		if report, ok := ctx.Value(load_report).(*LoadReport); ok {
+			list.Rejected, list.Warnings = report.Rejected, report.Warnings
+			for _, section := range []struct {
+				title  string
+				issues []*LoadIssue
+			}{{"未加载", report.Rejected}, {"签名异常", report.Warnings}} {
+				if len(section.issues) > 0 {
+					w.WriteString(section.title + ":\n")
+				}
+				for _, r := range section.issues {
+					w.WriteString(fmt.Sprintf("%s%s\n", indent, r.Error()))
+				}
+			}
+		}
+		return WithData(InfoMessage(0, w.String()), list)
 	})
 	_help = NewCommand("help", "使用方法,简述", BuildRun(func(ctx Context, args []string, flagmap FlagMap) Message {
 		return InfoMessage(0, helpFunc(ctx))
-	}, InputRules(ExactlyLength(0, ErrMessage(0, "不需要其它参数")))))
+	}, InputRules(ExactlyLength(0, ErrMessage(CodeUsage, "不需要其它参数")))))
 	_command     = NewRootCommand("command", "指令使用帮助信息")
 	_commandHelp = NewCommand("command help", "指令使用说明", BuildRun(func(ctx Context, args []string, flagmap FlagMap) Message {
 		return InfoMessage(0, commandHelp(ctx, args))
-	}, InputRules(ExpectLength(1, 2, nil))))
+	}, InputRules(ExpectLength(1, 0, nil))))
 
-	_commandSubs = NewCommand("command subs", "查看command下子指令用法", BuildRun(func(ctx Context, args []string, flagmap FlagMap) Message {
-		return InfoMessage(0, subsHelp(ctx, args[0]))
-	}, InputRules(ExactlyLength(1, nil))))
+	_commandSubs = WithCompleter(NewCommand("command subs", "查看command下子指令用法", BuildRun(func(ctx Context, args []string, flagmap FlagMap) Message {
+		return InfoMessage(0, subsHelp(ctx, args))
+	}, InputRules(ExpectLength(1, 0, nil)))), completeSubs)
 
-	_commandPlugin = NewCommand("command plugin", "查看plugin的下指令用法", BuildRun(func(ctx Context, args []string, flagmap FlagMap) Message {
+	_commandPlugin = WithCompleter(NewCommand("command plugin", "查看plugin的下指令用法", BuildRun(func(ctx Context, args []string, flagmap FlagMap) Message {
 		return InfoMessage(0, pluginHelp(ctx, args))
-	}, InputRules(ExactlyLength(1, nil))))
+	}, InputRules(ExactlyLength(1, nil)))), CompletePlugins)
+
+	_plugin     = NewRootCommand("plugin", "插件签名及可信公钥")
+	_pluginSign = WithCompleter(NewBindCommand("plugin sign", "用私钥签名插件,生成{file}.sig eg: plugin sign demo.so -key release.key",
+		func(ctx Context, args []string, opts *pluginSignOptions) Message {
+			sig, err := SignPluginFile(args[0], opts.Key)
+			if err != nil {
+				return WrapMessage(CodeInvalid, err, "签名插件失败").WithDetail("文件", args[0])
+			}
+			return WithData(InfoMessage(0, "已签名:%s\n公钥: %s\n摘要: %s", args[0]+signatureSuffix, sig.Key, sig.Digest), sig)
+		},
+		InputRules(ExactlyLength(1, nil)),
+	), CompletePath)
+	_pluginKeys = NewCommand("plugin keys", "查看可信公钥(-trust目录下的*.pub)", BuildRun(func(ctx Context, args []string, flagmap FlagMap) Message {
+		store, _ := ctx.Value(trust_store).(*TrustStore)
+		keys := store.Keys()
+		var w strings.Builder
+		if store != nil {
+			w.WriteString(fmt.Sprintf("可信公钥目录:%s\n", store.Dir))
+		}
+		for _, key := range keys {
+			w.WriteString(fmt.Sprintf("%s%s %s\n", indent, key.ID, key.Name))
+		}
+		if len(keys) == 0 {
+			w.WriteString("无可信公钥")
+		}
+		return WithData(InfoMessage(0, w.String()), keys)
+	}, InputRules(EmptyArgs())))
+	_pluginKeygen = NewBindCommand("plugin keygen", "生成签名密钥对{name}.key,{name}.pub eg: plugin keygen release -dir keys",
+		func(ctx Context, args []string, opts *pluginKeygenOptions) Message {
+			pub, err := GenerateKeyPair(opts.Dir, args[0])
+			if err != nil {
+				return WrapMessage(CodeInvalid, err, "生成密钥对失败")
+			}
+			pubFile := filepath.Join(opts.Dir, args[0]+publicKeySuffix)
+			return WithData(Rich(InfoMessage(0, "已生成密钥对 %s,公钥: %s", KeyID(pub), pubFile)).
+				WithHint("将公钥复制到可信公钥目录(-trust),私钥妥善保管"), TrustedKey{Name: args[0], ID: KeyID(pub), File: pubFile})
+		},
+		InputRules(ExactlyLength(1, nil)),
+	)
 
 	gogenCore = &GeneralPlugin{
 		ID:   core_name,
@@ -144,6 +280,13 @@ var (
 			_history,
 			_workspace,
 			_genplugin,
+			_completion,
+			_complete,
+			_source,
+			_plugin,
+			_pluginSign,
+			_pluginKeys,
+			_pluginKeygen,
 		},
 	}
 )
@@ -201,6 +344,7 @@ func pluginHelp(ctx Context, plugins []string) string {
 		}
 		aw := &AlignWriter{}
 		items, maxL = rc.Commands()
+		items = visibleCommands(items)
 		if len(items) == 0 {
 			aw.NopaddingAppend(fmt.Sprintf("插件%s注册指令: (空)\n", key))
 			return true
@@ -216,7 +360,7 @@ func pluginHelp(ctx Context, plugins []string) string {
 				usage = fmt.Sprintf(`使用:"command subs %s"获取更多信息`, cr.Key())
 			}
 
-			subItems, subL := cr.Children()
+			subItems, _ := cr.Children()
 			tips := subTips
 			if len(subItems) == 0 {
 				tips = ""
@@ -229,34 +373,10 @@ func pluginHelp(ctx Context, plugins []string) string {
 				aw.LeftPaddingAppend("[flags options]").NewLine()
 			}
 			for i := range flags {
-				f := flags[i]
-				if a, ok := f.Alias(); ok {
-					aw.LeftPaddingAppend(fmt.Sprintf("%s,%s %s", f.Name(), a, f.Usage())).SplitLine(-1, true)
-				} else {
-					aw.LeftPaddingAppend(fmt.Sprintf("%s %s", f.Name(), f.Usage())).SplitLine(-1, true)
-				}
-			}
-			if subL > maxSubL {
-				maxSubL = subL
+				aw.LeftPaddingAppend(flagHelp(flags[i])).SplitLine(-1, true)
 			}
-			var groups RegistryCommands = subItems
-			sort.Sort(groups)
-			for s := range groups {
-				sub := subItems[s]
-				aw.RightPaddingAppend(sub.Key()).Indent(maxL + 3)
-				aw.LeftPaddingAppend(sub.Usage()).SplitLine(-1, false)
-				flags := sub.Flags()
-				if len(flags) > 0 {
-					aw.LeftPaddingAppend("[flags options]").NewLine()
-				}
-				for i := range flags {
-					f := flags[i]
-					if a, ok := f.Alias(); ok {
-						aw.LeftPaddingAppend(fmt.Sprintf("%s,%s %s", f.Name(), a, f.Usage())).SplitLine(-1, true)
-					} else {
-						aw.LeftPaddingAppend(fmt.Sprintf("%s %s", f.Name(), f.Usage())).SplitLine(-1, true)
-					}
-				}
+			if l := appendSubsHelp(aw, subItems, maxL+3); l > maxSubL {
+				maxSubL = l
 			}
 		}
 		str := aw.MaskString(maxL+maxSubL+4, false)
@@ -269,15 +389,114 @@ func pluginHelp(ctx Context, plugins []string) string {
 	return w.String()
 }
 
-func subsHelp(ctx Context, name string) string {
+// -name,--alias {type} usage (默认:v,必填,环境变量:KEY)
+func flagHelp(f Flag) string {
+	var w strings.Builder
+	w.WriteString(f.Name())
+	if a, ok := f.Alias(); ok {
+		w.WriteString(",")
+		w.WriteString(a)
+	}
+	def, ok := f.(FlagDefinition)
+	if ok && def.Arity() != 0 {
+		w.WriteString(fmt.Sprintf(" {%s}", def.TypeName()))
+	}
+	w.WriteString(" ")
+	w.WriteString(f.Usage())
+	if !ok {
+		return w.String()
+	}
+	notes := make([]string, 0, 3)
+	if v, has := def.DefValue(); has {
+		notes = append(notes, fmt.Sprintf("默认:%v", v))
+	}
+	if def.Required() {
+		notes = append(notes, "必填")
+	}
+	if env, has := def.EnvKey(); has {
+		notes = append(notes, fmt.Sprintf("环境变量:%s", env))
+	}
+	if len(notes) > 0 {
+		w.WriteString(fmt.Sprintf(" (%s)", strings.Join(notes, ",")))
+	}
+	return w.String()
+}
+
+// 递归输出子指令用法,返回子指令key占用的最大宽度
+func appendSubsHelp(aw *AlignWriter, subs RegistryCommands, indent int) (maxL int) {
+	subs = visibleCommands(subs)
+	sort.Sort(subs)
+	for i := range subs {
+		sub := subs[i]
+		if len(sub.Key()) > maxL {
+			maxL = len(sub.Key())
+		}
+		aw.RightPaddingAppend(sub.Key()).Indent(indent)
+		aw.LeftPaddingAppend(sub.Usage()).SplitLine(-1, false)
+		flags := sub.Flags()
+		if len(flags) > 0 {
+			aw.LeftPaddingAppend("[flags options]").NewLine()
+		}
+		for i := range flags {
+			aw.LeftPaddingAppend(flagHelp(flags[i])).SplitLine(-1, true)
+		}
+		if children, _ := sub.Children(); len(children) > 0 {
+			if l := appendSubsHelp(aw, children, indent+2) + 2; l > maxL {
+				maxL = l
+			}
+		}
+	}
+	return
+}
+
+// 过滤隐藏指令
+func visibleCommands(items RegistryCommands) RegistryCommands {
+	visible := make(RegistryCommands, 0, len(items))
+	for i := range items {
+		if !items[i].IsHidden() {
+			visible = append(visible, items[i])
+		}
+	}
+	return visible
+}
+
+// 按已输入的指令路径补全下一层指令
+func completeSubs(ctx Context, args Args, current string) []string {
+	if len(args) == 0 {
+		return CompleteRootCommands(ctx, args, current)
+	}
+	root, ok := registrey(ctx).RootCommand(args[0])
+	if !ok {
+		return nil
+	}
+	node, ok := root.Descendant(args[1:]...)
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0, len(node.SubCommands))
+	for key, sub := range node.SubCommands {
+		if !sub.IsHidden() {
+			keys = append(keys, key)
+		}
+	}
+	return withPrefix(keys, current)
+}
+
+func subsHelp(ctx Context, path []string) string {
 	var w strings.Builder
 	r := registrey(ctx)
-	rc, ok := r.RootCommand(name)
+	name := strings.Join(path, " ")
+	root, ok := r.RootCommand(path[0])
+	var rc *RegisteredCommand
+	if ok {
+		rc, ok = root.Descendant(path[1:]...)
+	}
 	if !ok {
 		w.WriteString(fmt.Sprintf("查无%s指令", name))
 		return w.String()
 	}
 	items, maxL := rc.Children()
+	items = visibleCommands(items)
 	if len(items) == 0 {
 		w.WriteString("没有可用的子指令`")
 	} else {
@@ -286,10 +505,14 @@ func subsHelp(ctx Context, name string) string {
 		sort.Sort(children)
 		for i := range children {
 			c := children[i]
+			usage := c.Usage()
+			if subs, has := c.HasSub(); has && len(subs) > 0 {
+				usage = fmt.Sprintf(`%s (使用:"command subs %s %s"获取更多信息)`, usage, name, c.Key())
+			}
 			w.WriteString(fmt.Sprintf("%s%s %s",
 				indent,
 				fmt.Sprintf("%s%s", c.Key(), strings.Repeat(" ", maxL-len(c.Key()))),
-				SplitLines(c.Usage(), twidth, maxL+3, false)))
+				SplitLines(usage, twidth, maxL+3, false)))
 		}
 	}
 	return w.String()
@@ -304,6 +527,9 @@ func commandHelp(ctx Context, args []string) string {
 		return w.String()
 	}
 	w.WriteString(fmt.Sprintf("%s: %s", find.Key(), SplitLines(find.Usage(), twidth, len(find.Key())+1, false)))
+	if ac, ok := commandAs[AliasCommand](find.Command); ok && len(ac.Aliases()) > 0 {
+		w.WriteString(fmt.Sprintf("别名: %s\n", strings.Join(ac.Aliases(), ", ")))
+	}
 	return w.String()
 }
 
@@ -319,8 +545,9 @@ func helpFunc(ctx Context) string {
 	max, _ := r.RootKeyMaxLen()
 	var slice RegistryCommands = make([]*RegisteredCommand, 0, 5)
 	r.RangeRootCommand(func(key string, roots map[string]*RegisteredCommand) (next bool) {
-		rc := roots[key]
-		slice = append(slice, rc)
+		if rc := roots[key]; !rc.IsHidden() {
+			slice = append(slice, rc)
+		}
 		return true
 	})
 	sort.Sort(slice)
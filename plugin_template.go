@@ -11,8 +11,6 @@ import (
 var tpl = `package main
 
 import (
-	"fmt"
-
 	"yycelab.com/gocli"
 )
 // 确保引入 yycelab.com/gocli {版本同主程序一致},eg: go get yycelab.com/gocli@latest
@@ -52,13 +50,14 @@ func Setup(context gocli.Context) error {
 	return nil
 }
 
-// 已完成所有插件初始化完,运行环境检查,比如依赖处理等等
+// 已完成所有插件初始化完,运行环境检查;
+// 依赖的插件及核心版本在 Deps,MinCore 中声明,启动时自动检查
 func BeforeRun(ctx gocli.Context) error {
-	pluginMap := ctx.RegisteredPlugins()
-	coreId := "gogen_core"
-	if v, ok := pluginMap[coreId]; !ok || v.Md5() != "N/A" {
-		return fmt.Errorf("未找到%s,version:%s,md5:%s", coreId, "v0.0.1", "N/A")
-	}
+	return nil
+}
+
+// 程序退出前调用(按插件安装的逆序),ctx带有截止时间,用于写入文件,关闭连接等
+func Shutdown(ctx gocli.Context) error {
 	return nil
 }
 
@@ -87,11 +86,15 @@ var (
 )
 
 var Plugin = gocli.GeneralPlugin{
-	ID:     name,
-	Ver:    version,
-	Desc:   usage,
-	Init:   Setup,
-	PreRun: BeforeRun,
+	ID:      name,
+	Ver:     version,
+	Desc:    usage,
+	Init:    Setup,
+	PreRun:  BeforeRun,
+	Stop:    Shutdown,
+	MinCore: "{{.CoreVersion}}",
+	// 依赖的插件及版本约束,如 >=1.2.0 <2,^1.2.0,~1.2
+	// Deps: map[string]string{"db_plugin": "^1.2.0"},
 	Commands: []gocli.Command{
 		cmd_root,
 		command_sub,
@@ -105,11 +108,19 @@ type PluginBean struct {
 	Version    string
 	ExportType string
 	Usage      string
+	// 要求的最低核心版本,默认为当前核心版本
+	CoreVersion string
 }
 
 var pluginTemplate *template.Template
 
 func RenderPluginFile(bean *PluginBean, dest string) (err error) {
+	if _, err := ParseVersion(bean.Version); err != nil {
+		return err
+	}
+	if len(bean.CoreVersion) == 0 {
+		bean.CoreVersion = CoreVersion
+	}
 	fp, created, err := DirEnsure(dest, true)
 	if err != nil {
 		return err
@@ -1,6 +1,10 @@
 package gocli
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestRenderFile(t *testing.T) {
 	err := RenderPluginFile(&PluginBean{
@@ -14,3 +18,13 @@ func TestRenderFile(t *testing.T) {
 	}
 	t.Log("succ!")
 }
+
+func TestRenderInvalidVersion(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "plugin.go")
+	if err := RenderPluginFile(&PluginBean{Name: "demo", Version: "latest"}, dest); err == nil {
+		t.Error("invalid version should be rejected")
+	}
+	if _, err := os.Stat(dest); err == nil {
+		t.Error("plugin file should not be created")
+	}
+}
@@ -3,8 +3,8 @@ package gocli
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
-	"sync"
 	"unsafe"
 )
 
@@ -20,31 +20,45 @@ type Registry interface {
 	RootKeyMaxLen() (int, bool)
 	//支持 root command ,和subcommand
 	Command(args []string) (RegisteredCommand, bool)
+	//指令别名,完整匹配
+	Alias(args []string) (RegisteredCommand, bool)
+	//所有可执行指令的完整key,包括别名
+	CommandKeys() []string
 	CommandKeyMaxLen() (int, bool)
 	Plugin(name string) (RegisteredPlugin, bool)
 	FindPlugin(plugin Plugin) (RegisteredPlugin, bool)
 	RegisterCommand(p Plugin, cmds ...Command) (ok bool, err error)
-	RegisterPlugins(plugins ...Plugin)
+	// 版本不符合语义化版本的插件不注册,返回错误
+	RegisterPlugins(plugins ...Plugin) error
 	RangeRootCommand(RootCommandVisitor)
 	RangePlugin(PluginVisitor)
+	//按依赖排序的插件,被依赖的在前
+	InstallOrder() ([]*RegisteredPlugin, error)
 	Finish(panicunfinished bool) (loaded int, failed int)
 	Logger(log Log)
+	//全局严格模式,所有指令拒绝未声明的flag
+	Strict(strict bool)
+	IsStrict() bool
 }
 
 func NewRegistry() Registry {
 	return &registration{
 		plugins: make(map[string]*RegisteredPlugin, 5),
 		roots:   make(map[string]*RegisteredCommand, 13),
+		aliases: make(map[string]*RegisteredCommand, 5),
 	}
 }
 
 type registration struct {
 	plugins      map[string]*RegisteredPlugin
+	names        []string //插件注册顺序
 	roots        map[string]*RegisteredCommand
 	commands     map[string]*RegisteredCommand
+	aliases      map[string]*RegisteredCommand
 	rootsMaxL    int
 	commandsMaxL int
 	dofinish     bool
+	strict       bool
 	log          Log
 	// mux     sync.Mutex
 }
@@ -53,6 +67,14 @@ func (r *registration) Logger(log Log) {
 	r.log = log
 }
 
+func (r *registration) Strict(strict bool) {
+	r.strict = strict
+}
+
+func (r *registration) IsStrict() bool {
+	return r.strict
+}
+
 func (r *registration) Command(args []string) (RegisteredCommand, bool) {
 	keys := strings.Join(args, " ")
 	var rc RegisteredCommand
@@ -63,6 +85,44 @@ func (r *registration) Command(args []string) (RegisteredCommand, bool) {
 	return rc, ok
 }
 
+func (r *registration) Alias(args []string) (RegisteredCommand, bool) {
+	var rc RegisteredCommand
+	v, ok := r.aliases[strings.Join(args, " ")]
+	if ok {
+		rc = *v
+	}
+	return rc, ok
+}
+
+func (r *registration) CommandKeys() []string {
+	keys := make([]string, 0, len(r.commands))
+	for k, c := range r.commands {
+		if !c.IsHidden() {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (r *registration) registerAlias(node *RegisteredCommand, cmd Command) {
+	ac, ok := commandAs[AliasCommand](cmd)
+	if !ok {
+		return
+	}
+	for _, alias := range ac.Aliases() {
+		key := strings.Join(strings.Fields(alias), " ")
+		if len(key) == 0 {
+			continue
+		}
+		if v, found := r.aliases[key]; found && v != node {
+			r.log.Warn("指令%s的别名%s已被%s使用", cmd.Key(), key, v.Command.Key())
+			continue
+		}
+		r.aliases[key] = node
+	}
+}
+
 func (r *registration) RootKeyMaxLen() (int, bool) {
 	return r.rootsMaxL, r.dofinish
 }
@@ -85,22 +145,26 @@ func (r *registration) Finish(panicunfinished bool) (loaded int, failed int) {
 	commands := make(map[string]*RegisteredCommand, 13)
 	r.RangeRootCommand(func(key string, roots map[string]*RegisteredCommand) (next bool) {
 		root := roots[key]
-		if root.Command != nil {
-			commands[root.Key()] = root
-		}
 		if len(root.Key()) > r.rootsMaxL {
 			r.rootsMaxL = len(root.Key())
 		}
-		root.Range(func(key string, subs map[string]*RegisteredCommand) (next bool) {
-			sc := subs[key].Command
-			commands[sc.Key()] = subs[key]
-			if len(sc.Key()) > r.commandsMaxL {
-				r.commandsMaxL = len(sc.Key())
+		root.Walk(func(path []string, c *RegisteredCommand) {
+			if c.Command == nil {
+				return
+			}
+			fullKey := strings.Join(path, " ")
+			commands[fullKey] = c
+			if len(path) > 1 && len(fullKey) > r.commandsMaxL {
+				r.commandsMaxL = len(fullKey)
 			}
-			return true
 		})
 		return true
 	})
+	for alias, c := range r.aliases {
+		if _, found := commands[alias]; !found {
+			commands[alias] = c
+		}
+	}
 	r.commands = commands
 	r.dofinish = true
 	return
@@ -142,6 +206,7 @@ func (r *registration) RegisterCommand(p Plugin, cmds ...Command) (ok bool, err
 			root = NewRootRegistry(plugin.ptr, rootKey)
 			if len(keys) == 1 {
 				root.RootCommand(cmd)
+				r.registerAlias(root, cmd)
 			}
 			r.roots[rootKey] = root
 			err := plugin.Append(root)
@@ -150,6 +215,11 @@ func (r *registration) RegisterCommand(p Plugin, cmds ...Command) (ok bool, err
 			err = fmt.Errorf("根指令%s已注册:%+v,请求:%+v", rootKey, root.From, plugin.ptr)
 			logger.Debug(err.Error())
 			return
+		} else if len(keys) == 1 && root.Command == nil {
+			root.RootCommand(cmd)
+			r.registerAlias(root, cmd)
+			err := plugin.Append(root)
+			logger.Debug("%sregister command %s,err:%+v", plugin.Name(), root.Key(), err)
 		}
 		if len(keys) > 1 {
 			add, found, e := root.AddSub(plugin.ptr, cmd)
@@ -159,6 +229,7 @@ func (r *registration) RegisterCommand(p Plugin, cmds ...Command) (ok bool, err
 				return
 			}
 			if e == nil {
+				r.registerAlias(add, cmd)
 				err := plugin.Append(add)
 				logger.Debug("%s register subs command [%s] success ,err:%+v", plugin.Name(), cmd.Key(), err)
 			}
@@ -167,11 +238,15 @@ func (r *registration) RegisterCommand(p Plugin, cmds ...Command) (ok bool, err
 	ok = true
 	return
 }
-func (r *registration) RegisterPlugins(plugins ...Plugin) {
-
+func (r *registration) RegisterPlugins(plugins ...Plugin) error {
+	problems := make([]string, 0)
 	for i := range plugins {
 		p := plugins[i]
 
+		if err := checkVersion(p); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
 		v, ok := r.plugins[p.Name()]
 		if ok && p != v.Plugin {
 			panic(fmt.Sprintf("插件注册:重复%s,已注册:%+v%s,请求:%+v%s", p.Name(), v, v.Version(), p, p.Version()))
@@ -184,7 +259,10 @@ func (r *registration) RegisterPlugins(plugins ...Plugin) {
 		lp, ok := p.(*LoadedPlugin)
 		if ok {
 			rp.file = lp.File
-			rp.sign = &signature{kind: algorithm_md5, digest: lp.Digest, verified: lp.Verified}
+			rp.sign = lp.sign
+		}
+		if _, exist := r.plugins[p.Name()]; !exist {
+			r.names = append(r.names, p.Name())
 		}
 		r.plugins[p.Name()] = rp
 		r.log.Debug("注册插件%s", p.Name())
@@ -193,28 +271,28 @@ func (r *registration) RegisterPlugins(plugins ...Plugin) {
 			panic("插件注册:注册指令失败")
 		}
 	}
+	if len(problems) > 0 {
+		return fmt.Errorf("插件注册: %s", strings.Join(problems, "; "))
+	}
+	return nil
 }
 
 func (r *registration) removeCommand(p *RegisteredPlugin) bool {
-	nboot := make(map[string]*RegisteredCommand, len(r.roots))
-	ptr := p.ptr
+	nroots := make(map[string]*RegisteredCommand, len(r.roots))
 	for k, rc := range r.roots {
-		subs, ok := rc.HasSub()
-		if rc.From == ptr && !ok {
-			continue
+		keep, ok := rc.without(p.ptr)
+		if !ok {
+			return false
 		}
-		nsub := make(map[string]*RegisteredCommand, len(subs))
-		for sk, src := range subs {
-			if src.From == ptr {
-				continue
-			}
-			nsub[sk] = src
+		if keep {
+			nroots[k] = rc
 		}
-		if rc.From == ptr && len(nsub) > 0 {
-			return false
+	}
+	r.roots = nroots
+	for k, c := range r.aliases {
+		if c.From == p.ptr {
+			delete(r.aliases, k)
 		}
-		rc.SubCommands = nsub
-		nboot[k] = rc
 	}
 	return true
 }
@@ -242,8 +320,7 @@ type RegisteredCommand struct {
 	From        unsafe.Pointer
 	SubCommands SubCommand
 	// mlsc        int //子指令最大长度
-	key  string
-	once sync.Once
+	key string
 	Command
 }
 
@@ -256,6 +333,53 @@ func (c *RegisteredCommand) Range(v RegisteredCommandVisitor) {
 	}
 }
 
+type CommandWalker = func(path []string, c *RegisteredCommand)
+
+// 深度优先遍历指令树,path为从根指令开始的key
+func (c *RegisteredCommand) Walk(w CommandWalker) {
+	c.walk(make([]string, 0, 3), w)
+}
+
+func (c *RegisteredCommand) walk(parent []string, w CommandWalker) {
+	path := append(parent[0:len(parent):len(parent)], c.key)
+	w(path, c)
+	for _, sub := range c.SubCommands {
+		sub.walk(path, w)
+	}
+}
+
+// 按子指令key逐层查找
+func (c *RegisteredCommand) Descendant(keys ...string) (*RegisteredCommand, bool) {
+	node := c
+	for i := range keys {
+		sub, ok := node.SubCommands[keys[i]]
+		if !ok {
+			return nil, false
+		}
+		node = sub
+	}
+	return node, true
+}
+
+// 移除ptr注册的指令,keep为false表示该节点应被移除;ok为false表示存在冲突
+func (c *RegisteredCommand) without(ptr unsafe.Pointer) (keep bool, ok bool) {
+	nsub := make(map[string]*RegisteredCommand, len(c.SubCommands))
+	for k, sub := range c.SubCommands {
+		skeep, sok := sub.without(ptr)
+		if !sok {
+			return false, false
+		}
+		if skeep {
+			nsub[k] = sub
+		}
+	}
+	if c.From == ptr && len(nsub) > 0 {
+		return false, false
+	}
+	c.SubCommands = nsub
+	return c.From != ptr, true
+}
+
 func (c *RegisteredCommand) Children() (children []*RegisteredCommand, maxL int) {
 	children = make([]*RegisteredCommand, 0, len(c.SubCommands))
 	for k := range c.SubCommands {
@@ -267,6 +391,10 @@ func (c *RegisteredCommand) Children() (children []*RegisteredCommand, maxL int)
 	return
 }
 
+func (c *RegisteredCommand) IsHidden() bool {
+	return c.Command != nil && isHidden(c.Command)
+}
+
 func (c *RegisteredCommand) Usage() string {
 	if c.Command != nil {
 		return c.Command.Usage()
@@ -310,31 +438,60 @@ func (c *RegisteredCommand) SetSubs(subs ...*RegisteredCommand) {
 	c.SubCommands = tmp
 }
 
+// 按key逐层查找子指令,create为true时补全缺失的中间节点
+func (c *RegisteredCommand) descend(ptr unsafe.Pointer, keys []string, create bool) (*RegisteredCommand, error) {
+	node := c
+	for i := range keys {
+		if node.SubCommands == nil {
+			node.SubCommands = make(map[string]*RegisteredCommand, 5)
+		}
+		sub, ok := node.SubCommands[keys[i]]
+		if !ok {
+			if !create {
+				return nil, fmt.Errorf("%s下没有子指令%s", node.key, keys[i])
+			}
+			sub = NewRootRegistry(ptr, keys[i])
+			node.SubCommands[keys[i]] = sub
+		}
+		node = sub
+	}
+	return node, nil
+}
+
+func (c *RegisteredCommand) subKeys(key string) ([]string, error) {
+	keys := strings.Fields(key)
+	if len(keys) < 2 {
+		return nil, fmt.Errorf("%s不是子指令", key)
+	}
+	if keys[0] != c.key {
+		return nil, fmt.Errorf("不是%s的子命令", c.key)
+	}
+	return keys[1:], nil
+}
+
 func (c *RegisteredCommand) AppendSub(sub *RegisteredCommand) (ok bool, err error) {
 	if sub.Command == nil {
 		err = errors.New("子指令不可为空")
 		return
 	}
-	keys := strings.Fields(sub.Command.Key())
-	if len(keys) != 2 {
-		err = errors.New("命令只能两层")
+	keys, err := c.subKeys(sub.Command.Key())
+	if err != nil {
 		return
 	}
-	p := keys[0]
-	if p != c.key {
-		err = fmt.Errorf("不是%s的子命令", c.key)
+	parent, err := c.descend(sub.From, keys[0:len(keys)-1], true)
+	if err != nil {
 		return
 	}
-	subkey := keys[1]
-	v, found := c.SubCommands[subkey]
-	if found && v.Command != sub.Command {
+	subkey := keys[len(keys)-1]
+	v, found := parent.SubCommands[subkey]
+	if found && v.Command != nil && v.Command != sub.Command {
 		err = fmt.Errorf("%s已经存在%+v", subkey, v)
 		return
 	}
-	// if len(subkey) > c.mlsc {
-	// 	c.mlsc = len(subkey)
-	// }
-	c.SubCommands[subkey] = sub
+	if found && v != sub {
+		sub.SubCommands = v.SubCommands
+	}
+	parent.SubCommands[subkey] = sub
 	ok = true
 	return
 }
@@ -344,36 +501,33 @@ func (c *RegisteredCommand) AddSub(ptr unsafe.Pointer, cmd Command) (add *Regist
 		err = errors.New("子指令不可为空")
 		return
 	}
-	keys := strings.Fields(cmd.Key())
-	if len(keys) != 2 {
-		err = errors.New("命令只能两层")
+	keys, err := c.subKeys(cmd.Key())
+	if err != nil {
 		return
 	}
-	p := keys[0]
-	if p != c.key {
-		err = fmt.Errorf("不是%s的子命令", c.key)
+	parent, err := c.descend(ptr, keys[0:len(keys)-1], true)
+	if err != nil {
 		return
 	}
-	subKey := keys[1]
-	c.once.Do(func() {
-		if c.SubCommands == nil {
-			c.SubCommands = make(map[string]*RegisteredCommand, 5)
-		}
-	})
-	v, ok := c.SubCommands[subKey]
-	if ok {
+	subKey := keys[len(keys)-1]
+	v, found := parent.SubCommands[subKey]
+	if found && v.Command != nil {
 		err = fmt.Errorf("%s已经存在%+v", subKey, v)
 		return
 	}
-	// if len(subKey) > c.mlsc {
-	// 	c.mlsc = len(subKey)
-	// }
-	add = &RegisteredCommand{
-		Command: cmd,
-		key:     subKey,
-		From:    ptr,
+	if found {
+		// 先注册的更深层指令补全的中间节点
+		v.Command = cmd
+		v.From = ptr
+		add = v
+	} else {
+		add = &RegisteredCommand{
+			Command: cmd,
+			key:     subKey,
+			From:    ptr,
+		}
+		parent.SubCommands[subKey] = add
 	}
-	c.SubCommands[subKey] = add
 	ok = true
 	return
 }
@@ -407,31 +561,33 @@ func NewRootRegistry(from unsafe.Pointer, key string) *RegisteredCommand {
 type algorithm string
 
 const (
-	algorithm_md5 algorithm = "md5"
+	algorithm_sha256  algorithm = "sha256"
+	algorithm_ed25519 algorithm = "ed25519"
 )
 
+// 插件文件的摘要(sha256)及签名校验结果
 type signature struct {
 	kind     algorithm
 	digest   string
+	key      string
+	signer   string
 	verified bool
 }
 
 type RegisteredPlugin struct {
 	Plugin
+	deps   []*RegisteredPlugin
 	sign   *signature
 	finish bool
 	ptr    unsafe.Pointer
 	file   string
 	root   map[string]*RegisteredCommand
-	once   sync.Once
 }
 
 func (rp *RegisteredPlugin) init() {
-	rp.once.Do(func() {
-		if rp.root == nil {
-			rp.root = make(map[string]*RegisteredCommand, 7)
-		}
-	})
+	if rp.root == nil {
+		rp.root = make(map[string]*RegisteredCommand, 7)
+	}
 }
 
 func (rp *RegisteredPlugin) Commands() (commands []*RegisteredCommand, maxL int) {
@@ -466,39 +622,124 @@ func (rp *RegisteredPlugin) Append(c *RegisteredCommand) error {
 		return fmt.Errorf("该指令已经注册在别的插件下")
 	}
 	if !ok {
-		v = NewRootRegistry(c.From, key)
+		v = NewRootRegistry(c.From, keys[0])
 		rp.root[keys[0]] = v
 	}
 	if len(keys) == 1 && c.Command != nil && v.Command == nil {
 		v.Command = c.Command
 	}
 	if len(keys) > 1 {
-		v.AppendSub(c)
+		_, err := v.AppendSub(&RegisteredCommand{From: c.From, key: keys[len(keys)-1], Command: c.Command})
+		return err
 	}
 	return nil
 }
 
+// 最长前缀匹配,未匹配的输入作为参数
 func matchCommand(registry Registry, args []string) (c RegisteredCommand, cargs Args, matched bool) {
+	c, cargs, msg := resolveCommand(registry, args)
+	return c, cargs, msg == nil
+}
+
+// 依次按别名,指令key及其唯一前缀(sh hist => show history)匹配,取匹配输入最多的指令;
+// 父指令可执行时子指令不按前缀匹配;前缀不唯一时返回候选列表
+func resolveCommand(registry Registry, args []string) (c RegisteredCommand, cargs Args, msg Message) {
 	if len(args) == 0 {
+		msg = WarnMessage(CodeUsage, "no command found")
 		return
 	}
-	c, matched = registry.RootCommand(args[0])
-	if !matched {
-		return
+	aliasN := 0
+	for n := len(args); n > 0; n-- {
+		if ac, ok := registry.Alias(args[0:n]); ok {
+			c, cargs, aliasN = ac, args[n:], n
+			break
+		}
 	}
-
-	cargs = args[1:]
-	if subs, has := c.HasSub(); has && len(args) > 1 {
-		sc, ok := subs[args[1]]
-		if ok {
-			cargs = cargs[1:]
-			c = *sc
+	roots := make(map[string]*RegisteredCommand, 13)
+	registry.RangeRootCommand(func(key string, all map[string]*RegisteredCommand) (next bool) {
+		roots[key] = all[key]
+		return true
+	})
+	node, candidates := abbrevMatch(roots, args[0])
+	if node == nil {
+		if aliasN > 0 {
 			return
 		}
+		if len(candidates) > 1 {
+			msg = ambiguousMessage(args[0:1], candidates)
+		} else {
+			msg = notFoundMessage(registry, args)
+		}
+		return
+	}
+	var (
+		found *RegisteredCommand
+		n     int
+	)
+	if node.Command != nil {
+		found, n = node, 1
+	}
+	for i := 1; i < len(args); i++ {
+		var (
+			sub        *RegisteredCommand
+			candidates []string
+		)
+		if node.Command != nil {
+			// 父指令可执行时其后的输入可能是参数,子指令只做完整匹配
+			sub = node.SubCommands[args[i]]
+		} else {
+			sub, candidates = abbrevMatch(node.SubCommands, args[i])
+		}
+		if sub == nil {
+			if len(candidates) > 1 && aliasN <= i {
+				msg = ambiguousMessage(args[0:i+1], candidates)
+				return
+			}
+			break
+		}
+		node = sub
+		if node.Command != nil {
+			found, n = node, i+1
+		}
 	}
-	if c.Command == nil {
-		matched = false
+	if found == nil || n < aliasN {
+		if aliasN == 0 {
+			msg = notFoundMessage(registry, args)
+		}
 		return
 	}
-	return
+	return *found, args[n:], nil
+}
+
+// 完整匹配优先,否则取唯一的前缀匹配;空输入不匹配
+func abbrevMatch(commands map[string]*RegisteredCommand, input string) (*RegisteredCommand, []string) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+	if c, ok := commands[input]; ok {
+		return c, nil
+	}
+	candidates := make([]string, 0, 3)
+	var found *RegisteredCommand
+	for key, c := range commands {
+		if strings.HasPrefix(key, input) && !c.IsHidden() {
+			candidates = append(candidates, key)
+			found = c
+		}
+	}
+	if len(candidates) == 1 {
+		return found, candidates
+	}
+	sort.Strings(candidates)
+	return nil, candidates
+}
+
+func ambiguousMessage(input []string, candidates []string) Message {
+	prefix := strings.Join(input[0:len(input)-1], " ")
+	if len(prefix) > 0 {
+		for i := range candidates {
+			candidates[i] = fmt.Sprintf("%s %s", prefix, candidates[i])
+		}
+	}
+	return WarnMessage(CodeAmbiguous, "指令%s不明确,可能是: %s", strings.Join(input, " "), strings.Join(candidates, ", "))
 }
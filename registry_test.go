@@ -0,0 +1,213 @@
+package gocli
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func testRegistry(plugins ...Plugin) Registry {
+	r := NewRegistry()
+	r.Logger(NewConsole(nil, nil).(*mixedConsole))
+	r.RegisterPlugins(plugins...)
+	r.Finish(false)
+	return r
+}
+
+func testContext(t *testing.T, plugins ...Plugin) *context {
+	console := NewConsole(nil, NewLogger(filepath.Join(t.TempDir(), "test.log")))
+	console.Level(LOG_ERROR)
+	return &context{
+		console:   console,
+		registrey: testRegistry(plugins...),
+		interrupt: &atomic.Bool{},
+		workdir:   t.TempDir(),
+	}
+}
+
+func echoCommand(key string) Command {
+	return NewCommand(key, key, func(ctx Context, args []string, flagmap FlagMap) Message {
+		return InfoMessage(0, key)
+	})
+}
+
+func TestNestedCommands(t *testing.T) {
+	r := testRegistry(&GeneralPlugin{
+		ID: "db_plugin",
+		Commands: []Command{
+			echoCommand("db migrate up"),
+			echoCommand("db migrate down"),
+			echoCommand("db"),
+			echoCommand("k8s pod logs"),
+		},
+	})
+	cases := []struct {
+		input Args
+		key   string
+		args  Args
+	}{
+		{Args{"db", "migrate", "up", "3"}, "db migrate up", Args{"3"}},
+		{Args{"db", "migrate", "down"}, "db migrate down", Args{}},
+		{Args{"db", "migrate"}, "db", Args{"migrate"}},
+		{Args{"db", "status"}, "db", Args{"status"}},
+		{Args{"k8s", "pod", "logs", "web"}, "k8s pod logs", Args{"web"}},
+	}
+	for _, c := range cases {
+		rc, args, ok := matchCommand(r, c.input)
+		if !ok {
+			t.Fatalf("%q: not matched", c.input)
+		}
+		if rc.Command.Key() != c.key || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("%q: expect %s %q, got %s %q", c.input, c.key, c.args, rc.Command.Key(), args)
+		}
+	}
+	if _, _, ok := matchCommand(r, Args{"k8s", "pod"}); ok {
+		t.Error("k8s pod has no command, should not match")
+	}
+	if _, ok := r.Command(Args{"db", "migrate", "up"}); !ok {
+		t.Error("registry should find db migrate up")
+	}
+}
+
+func TestAliasAndPrefixMatch(t *testing.T) {
+	r := testRegistry(&GeneralPlugin{
+		ID: "show_plugin",
+		Commands: []Command{
+			WithAliases(echoCommand("show history"), "history", "hist"),
+			echoCommand("show plugins"),
+			echoCommand("shutdown"),
+			echoCommand("db migrate"),
+			echoCommand("db mount"),
+		},
+	})
+	cases := []struct {
+		input Args
+		key   string
+		args  Args
+	}{
+		{Args{"history"}, "show history", Args{}},
+		{Args{"hist", "3"}, "show history", Args{"3"}},
+		{Args{"sho", "hist"}, "show history", Args{}},
+		{Args{"show", "p", "x"}, "show plugins", Args{"x"}},
+		{Args{"shu"}, "shutdown", Args{}},
+		{Args{"db", "mi"}, "db migrate", Args{}},
+	}
+	for _, c := range cases {
+		rc, args, msg := resolveCommand(r, c.input)
+		if msg != nil {
+			t.Fatalf("%q: %s", c.input, msg.Msg())
+		}
+		if rc.Command.Key() != c.key || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("%q: expect %s %q, got %s %q", c.input, c.key, c.args, rc.Command.Key(), args)
+		}
+	}
+	for _, input := range []Args{{"sh"}, {"db", "m"}} {
+		_, _, msg := resolveCommand(r, input)
+		if msg == nil || msg.Code() != 300 {
+			t.Errorf("%q should be ambiguous, got %+v", input, msg)
+		}
+	}
+	if _, _, msg := resolveCommand(r, Args{"nothing"}); msg == nil || msg.Code() != 404 {
+		t.Errorf("expect 404, got %+v", msg)
+	}
+	if _, _, msg := resolveCommand(r, Args{""}); msg == nil || msg.Code() != 404 {
+		t.Errorf("empty input should not match, got %+v", msg)
+	}
+
+	// 可执行的父指令后的输入作为参数,不按前缀匹配子指令
+	r = testRegistry(&GeneralPlugin{ID: "db_plugin", Commands: []Command{echoCommand("db"), echoCommand("db migrate")}})
+	for _, c := range []struct {
+		input Args
+		key   string
+		args  Args
+	}{
+		{Args{"db", "m"}, "db", Args{"m"}},
+		{Args{"db", ""}, "db", Args{""}},
+		{Args{"db", "migrate"}, "db migrate", Args{}},
+	} {
+		rc, args, msg := resolveCommand(r, c.input)
+		if msg != nil || rc.Command.Key() != c.key || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("%q: expect %s %q, got %+v %q", c.input, c.key, c.args, msg, args)
+		}
+	}
+}
+
+func TestSuggestion(t *testing.T) {
+	r := testRegistry(gogenCore)
+	cases := map[string]string{
+		"shwo plugins": "`show plugins`",
+		"hlep":         "`help`",
+		"show histroy": "`show history`",
+		"histroy":      "`history`",
+	}
+	for input, expect := range cases {
+		tokens, _ := Tokenize(input)
+		_, _, msg := resolveCommand(r, tokens)
+		if msg == nil || !strings.Contains(FormatMessage(msg), expect) {
+			t.Errorf("%s: expect suggestion %s, got %+v", input, expect, msg)
+		}
+	}
+	_, _, _, msg := resolveInput(r, Args{"genplugin", "a.go", "-nme", "demo"})
+	if msg == nil || !strings.Contains(FormatMessage(msg), "`-nm`") {
+		t.Errorf("expect flag suggestion, got %+v", msg)
+	}
+}
+
+func TestOptionalFlagSuggestion(t *testing.T) {
+	cp := NewFlagsCommand("copy", "", echoCommand("copy").Run, StringFlag("dest"))
+	ctx := testContext(t, gogenCore, &GeneralPlugin{ID: "copy_plugin", Commands: []Command{cp}})
+	cases := map[string]string{
+		"copy a.txt -dset b":       "-dset: did you mean `-dest`?",
+		"ws -ouput json":           "-ouput: did you mean `--output`?",
+		"show plugins -frmat json": "-frmat: did you mean `-frame`?",
+	}
+	for input, expect := range cases {
+		tokens, _ := Tokenize(input)
+		c, args, fmap, msg := resolveInput(ctx.registry(), tokens)
+		if msg != nil {
+			t.Fatalf("%s: %s", input, FormatMessage(msg))
+		}
+		msg = runCommand(ctx, c, args, fmap)
+		if !strings.Contains(FormatMessage(msg), expect) {
+			t.Errorf("%s: expect hint %q, got %q", input, expect, FormatMessage(msg))
+		}
+		if _, ok := msg.(DataMessage); c.Command.Key() == "ws" && !ok {
+			t.Error("hint should keep the data of the result")
+		}
+	}
+
+	// 负数参数不是flag,不提示
+	c, args, fmap, _ := resolveInput(ctx.registry(), Args{"copy", "-0"})
+	if text := FormatMessage(runCommand(ctx, c, args, fmap)); strings.Contains(text, "did you mean") {
+		t.Errorf("negative number should not get a flag hint, got %q", text)
+	}
+
+	// 原结果不被修改,空结果保持为空
+	shared := Rich(InfoMessage(0, "done")).WithHint("原提示")
+	hinted := withFlagHint(WithData(shared, 1), "-x: did you mean `-o`?")
+	if shared.Hint() != "原提示" || !strings.HasSuffix(FormatMessage(hinted), "提示: 原提示; -x: did you mean `-o`?") {
+		t.Errorf("shared message should stay unchanged, got %q / %q", shared.Hint(), FormatMessage(hinted))
+	}
+	if withFlagHint(nil, "-x: did you mean `-o`?") != nil {
+		t.Error("nil result should stay nil")
+	}
+}
+
+func TestStrictFlags(t *testing.T) {
+	loose := echoCommand("loose")
+	strict := WithAliases(WithStrict(NewFlagsCommand("strict", "", loose.Run, StringFlag("nm"))), "st")
+	r := testRegistry(&GeneralPlugin{ID: "strict_plugin", Commands: []Command{loose, strict}})
+	if _, _, _, msg := resolveInput(r, Args{"loose", "-x"}); msg != nil {
+		t.Errorf("loose command should accept unknown flags, got %s", msg.Msg())
+	}
+	_, _, _, msg := resolveInput(r, Args{"st", "-nme", "demo", "-logl", "0"})
+	if msg == nil || !strings.Contains(FormatMessage(msg), "-nme") || !strings.Contains(FormatMessage(msg), "`-nm`") {
+		t.Errorf("strict command should reject -nme, got %+v", msg)
+	}
+	r.Strict(true)
+	if _, _, _, msg := resolveInput(r, Args{"loose", "-x"}); msg == nil {
+		t.Error("global strict mode should reject -x")
+	}
+}
@@ -0,0 +1,284 @@
+package gocli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// 行模式交互,不依赖tview全屏界面,适用于哑终端,CI日志及串口终端
+// 终端下支持行编辑,上下键历史及TAB补全,Ctrl-D退出
+type replui struct {
+	in         io.Reader
+	out        io.Writer
+	registry   Registry
+	context    Context
+	logger     Log
+	histories  [][]string
+	maxHistory int
+	// 中断信号,为空时监听进程的 SIGINT,SIGTERM
+	signals <-chan os.Signal
+}
+
+func NewRepl() Window {
+	return ConfigRepl(os.Stdin, os.Stdout, UiOptions{})
+}
+
+func ConfigRepl(in io.Reader, out io.Writer, options UiOptions) Window {
+	max := options.MaxHistoryItem
+	if max < 3 {
+		max = 10
+	}
+	return &replui{
+		in:         in,
+		out:        out,
+		histories:  make([][]string, 0, max),
+		maxHistory: max,
+	}
+}
+
+func (ui *replui) Run(prompt string, ctx Context) Message {
+	ui.registry = ctx.(registreyContext).registry()
+	log, ok := ctx.Logger()
+	if !ok {
+		return ErrMessage(-1, "获取logger失败")
+	}
+	ctx.SetValueIfAbsent(history_list, &ui.histories)
+	ui.context = ctx
+	ui.logger = log.NewLogger(history_log)
+
+	input, err := ui.lineReader(prompt)
+	if err != nil {
+		return ErrMessage(-1, "初始化终端失败:%s", err.Error())
+	}
+	defer input.mode(false)
+	ctx.SetValue(output_sink, NewWriterSink(ui.out))
+	defer ctx.Remove(output_sink)
+	signals := ui.signals
+	if signals == nil {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, exitSignals...)
+		defer signal.Stop(ch)
+		signals = ch
+	}
+	ui.println("输入help查看可用指令,quit或Ctrl-D退出")
+	for ctx.Err() == nil {
+		// 收到中断信号时不再等待输入
+		ch := make(chan lineRead, 1)
+		go func() {
+			line, err := input.read()
+			ch <- lineRead{line, err}
+		}()
+		var r lineRead
+		select {
+		case r = <-ch:
+		case <-ctx.Done():
+			return ui.exit(input, ch)
+		case <-signals:
+			// 等待输入时收到中断信号(非终端输入)即退出
+			return ui.exit(input, ch)
+		}
+		line, err := r.line, r.err
+		if err != nil {
+			if err != io.EOF {
+				return ErrMessage(-1, "读取输入失败:%s", err.Error())
+			}
+			ui.println("")
+			break
+		}
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if args, msg := Tokenize(line); msg == nil {
+			pushHistory(&ui.histories, ui.maxHistory, args)
+		}
+		// 执行期间终端为普通模式,Ctrl+C产生中断信号以取消指令
+		input.mode(false)
+		next := ui.run(line, signals)
+		input.mode(true)
+		if !next {
+			break
+		}
+	}
+	return InfoMessage(-1, "程序退出")
+}
+
+// 先停止读取,再由调用方恢复终端
+func (ui *replui) exit(input *lineInput, reading <-chan lineRead) Message {
+	if input.stop() {
+		<-reading
+	}
+	ui.println("")
+	return InfoMessage(-1, "程序退出")
+}
+
+// 在后台执行指令:中断信号只取消该指令,之后回到提示符;SIGTERM取消后退出。
+// 指令返回前保持等待,避免与下一条指令同时输出
+func (ui *replui) run(line string, signals <-chan os.Signal) bool {
+	jctx, cancel := WithCancel(ui.context)
+	defer cancel()
+	done := make(chan bool, 1)
+	go func() {
+		done <- ui.command(jctx, line)
+	}()
+	exit := false
+	for {
+		select {
+		case next := <-done:
+			return next && !exit
+		case sig := <-signals:
+			if jctx.Err() == nil {
+				ui.println("取消中...")
+			}
+			cancel()
+			exit = exit || sig == syscall.SIGTERM
+		}
+	}
+}
+
+type lineRead struct {
+	line string
+	err  error
+}
+
+type lineInput struct {
+	read func() (string, error)
+	// 停止等待中的读取,返回false表示无法中断
+	stop func() bool
+	// 切换终端模式,raw为false时恢复终端原来的状态
+	mode func(raw bool)
+}
+
+// 终端下使用x/term行编辑,否则逐行读取
+func (ui *replui) lineReader(prompt string) (*lineInput, error) {
+	if f, ok := ui.in.(*os.File); ok && term.IsTerminal(int(f.Fd())) && os.Getenv("TERM") != "dumb" {
+		fd := int(f.Fd())
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return nil, err
+		}
+		reader := newTTYReader(f)
+		t := term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{reader, ui.out}, prompt)
+		if w, h, err := term.GetSize(fd); err == nil && w > 0 {
+			t.SetSize(w, h)
+		}
+		t.AutoCompleteCallback = ui.autocomplete
+		ui.out = t
+		return &lineInput{read: t.ReadLine, stop: reader.Stop, mode: func(raw bool) {
+			if raw {
+				term.MakeRaw(fd)
+				return
+			}
+			term.Restore(fd, state)
+		}}, nil
+	}
+	scanner := bufio.NewScanner(ui.in)
+	return &lineInput{read: func() (string, error) {
+		fmt.Fprint(ui.out, prompt)
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", io.EOF
+		}
+		return scanner.Text(), nil
+	}, stop: func() bool { return false }, mode: func(raw bool) {}}, nil
+}
+
+// 返回false表示退出
+func (ui *replui) command(ctx Context, input string) bool {
+	var (
+		c     RegisteredCommand
+		cargs Args
+		fmap  FlagMap
+	)
+	tokens, perr := Tokenize(input)
+	if perr == nil {
+		c, cargs, fmap, perr = resolveInput(ui.registry, tokens)
+	}
+	if perr != nil {
+		ui.println(FormatMessage(perr))
+		return true
+	}
+	message := runCommand(ctx, c, cargs, fmap)
+	if message == nil {
+		ui.logger.Debug("command %s run return empty", c.Command.Key())
+		return true
+	}
+	if message.Code() < 0 {
+		ui.println(message.Msg())
+		return false
+	}
+	ui.println(FormatMessage(message))
+	ui.logger.Debug("command %s run result %s", c.Command.Key(), message.Msg())
+	return true
+}
+
+// TAB补全:唯一或有公共前缀时补全输入,否则列出候选项
+func (ui *replui) autocomplete(line string, pos int, key rune) (newLine string, newPos int, ok bool) {
+	if key != '\t' {
+		return
+	}
+	entries := completeLine(ui.context, ui.registry, line[0:pos])
+	if len(entries) == 0 {
+		return
+	}
+	common := commonPrefix(entries)
+	if len(entries) == 1 || len(common) > pos {
+		return common + line[pos:], len(common), true
+	}
+	base := common[0 : strings.LastIndex(common, " ")+1]
+	words := make([]string, len(entries))
+	for i := range entries {
+		words[i] = strings.TrimSpace(strings.TrimPrefix(entries[i], base))
+	}
+	ui.println(strings.Join(words, "  "))
+	return
+}
+
+func (ui *replui) println(msg string) {
+	fmt.Fprintln(ui.out, msg)
+}
+
+func commonPrefix(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	prefix := values[0]
+	for _, v := range values[1:] {
+		for !strings.HasPrefix(v, prefix) {
+			prefix = prefix[0 : len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// 添加历史,已存在则忽略,超出max时移除最早的记录
+func pushHistory(histories *[][]string, max int, args Args) {
+	if len(args) == 0 {
+		return
+	}
+	items := *histories
+	line := JoinArgs(args)
+	for i := range items {
+		if JoinArgs(items[i]) == line {
+			return
+		}
+	}
+	if len(items) >= max {
+		copy(items, items[1:])
+		items[len(items)-1] = args
+		return
+	}
+	*histories = append(items, args)
+}
@@ -0,0 +1,34 @@
+//go:build !unix
+
+package gocli
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// 不支持轮询的平台,停止后不再读取,但无法中断等待中的Read
+type ttyReader struct {
+	f    *os.File
+	stop chan struct{}
+	once sync.Once
+}
+
+func newTTYReader(f *os.File) *ttyReader {
+	return &ttyReader{f: f, stop: make(chan struct{})}
+}
+
+func (r *ttyReader) Read(p []byte) (int, error) {
+	select {
+	case <-r.stop:
+		return 0, io.EOF
+	default:
+	}
+	return r.f.Read(p)
+}
+
+func (r *ttyReader) Stop() bool {
+	r.once.Do(func() { close(r.stop) })
+	return false
+}
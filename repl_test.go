@@ -0,0 +1,124 @@
+package gocli
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRepl(t *testing.T) {
+	console := NewConsole(nil, NewLogger(filepath.Join(t.TempDir(), "repl.log")))
+	console.Level(LOG_ERROR)
+	ctx := &context{
+		console:   console,
+		registrey: testRegistry(gogenCore),
+		interrupt: &atomic.Bool{},
+	}
+	in := strings.NewReader("help\n\nshwo plugins\nshow history\nquit\nws\n")
+	var out bytes.Buffer
+	msg := ConfigRepl(in, &out, UiOptions{}).Run("> ", ctx)
+	if msg.Code() >= 0 {
+		t.Errorf("expect exit message, got %+v", msg)
+	}
+	output := out.String()
+	for _, expect := range []string{"主程序:gogen_core", "`show plugins`", "退出程序"} {
+		if !strings.Contains(output, expect) {
+			t.Errorf("output should contain %s:\n%s", expect, output)
+		}
+	}
+	if !strings.HasSuffix(output, "退出程序\n") {
+		t.Error("commands after quit should not run")
+	}
+	histories := *ctx.Value(history_list).(*[][]string)
+	expect := [][]string{{"help"}, {"shwo", "plugins"}, {"show", "history"}, {"quit"}}
+	if !reflect.DeepEqual(histories, expect) {
+		t.Errorf("expect histories %q, got %q", expect, histories)
+	}
+}
+
+func TestPushHistory(t *testing.T) {
+	histories := make([][]string, 0, 3)
+	for _, line := range []string{"a", "b", "a", "c", "d"} {
+		pushHistory(&histories, 3, Args{line})
+	}
+	if expect := [][]string{{"b"}, {"c"}, {"d"}}; !reflect.DeepEqual(histories, expect) {
+		t.Errorf("expect %q, got %q", expect, histories)
+	}
+}
+
+func TestReplSignalCancel(t *testing.T) {
+	started := make(chan struct{}, 1)
+	block := NewCommand("block", "等待取消", func(ctx Context, args []string, flagmap FlagMap) Message {
+		started <- struct{}{}
+		select {
+		case <-ctx.Done():
+			return WrapMessage(0, ctx.Err(), "指令已取消")
+		case <-time.After(2 * time.Second):
+			return InfoMessage(0, "未取消")
+		}
+	})
+	ctx := testContext(t, gogenCore, &GeneralPlugin{ID: "block_plugin", Commands: []Command{block}})
+	signals := make(chan os.Signal, 1)
+	go func() {
+		<-started
+		signals <- os.Interrupt
+	}()
+	var out bytes.Buffer
+	ui := ConfigRepl(strings.NewReader("block\nhelp\n"), &out, UiOptions{}).(*replui)
+	ui.signals = signals
+	msg := ui.Run("> ", ctx)
+	output := out.String()
+	if msg.Code() >= 0 || !strings.Contains(output, "取消中") || !strings.Contains(output, "指令已取消") {
+		t.Errorf("interrupt should cancel the running command:\n%s", output)
+	}
+	// 只取消指令,会话继续
+	if ctx.Err() != nil || !strings.Contains(output, "主程序:gogen_core") {
+		t.Errorf("repl should return to the prompt after the interrupt:\n%s", output)
+	}
+
+	// SIGTERM 取消指令后退出
+	go func() {
+		<-started
+		signals <- syscall.SIGTERM
+	}()
+	out.Reset()
+	ui = ConfigRepl(strings.NewReader("block\nhelp\n"), &out, UiOptions{}).(*replui)
+	ui.signals = signals
+	ui.Run("> ", ctx)
+	if output := out.String(); !strings.Contains(output, "指令已取消") || strings.Contains(output, "主程序:gogen_core") {
+		t.Errorf("SIGTERM should exit after cancelling:\n%s", output)
+	}
+}
+
+func TestTTYReaderStop(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	reader := newTTYReader(r)
+	done := make(chan error, 1)
+	go func() {
+		_, err := reader.Read(make([]byte, 8))
+		done <- err
+	}()
+	if !reader.Stop() {
+		t.Skip("read can not be interrupted on this platform")
+	}
+	select {
+	case err := <-done:
+		if err != io.EOF {
+			t.Errorf("expect EOF after stop, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("stop should interrupt the pending read")
+	}
+}
@@ -0,0 +1,56 @@
+//go:build unix
+
+package gocli
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// 终端输入,等待时轮询fd以便随时停止;停止后Read返回io.EOF
+type ttyReader struct {
+	fd   int
+	stop chan struct{}
+	once sync.Once
+}
+
+func newTTYReader(f *os.File) *ttyReader {
+	return &ttyReader{fd: int(f.Fd()), stop: make(chan struct{})}
+}
+
+func (r *ttyReader) Read(p []byte) (int, error) {
+	fds := []unix.PollFd{{Fd: int32(r.fd), Events: unix.POLLIN}}
+	for {
+		select {
+		case <-r.stop:
+			return 0, io.EOF
+		default:
+		}
+		ready, err := unix.Poll(fds, 100)
+		if err == unix.EINTR || ready == 0 {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		n, err := unix.Read(r.fd, p)
+		switch {
+		case err == unix.EINTR || err == unix.EAGAIN:
+			continue
+		case err != nil:
+			return 0, err
+		case n == 0:
+			return 0, io.EOF
+		}
+		return n, nil
+	}
+}
+
+// 返回true表示等待中的Read会随之返回
+func (r *ttyReader) Stop() bool {
+	r.once.Do(func() { close(r.stop) })
+	return true
+}
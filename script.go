@@ -0,0 +1,161 @@
+package gocli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	script_depth ContextKey = "script.depth"
+	// source嵌套执行脚本的最大层数
+	maxScriptDepth = 8
+)
+
+// 脚本中的一条指令,No为起始行号
+type ScriptLine struct {
+	No    int
+	Input string
+}
+
+// 读取脚本:每行一条指令,忽略空行及#开头的注释,行尾\续行
+func ReadScript(r io.Reader) ([]ScriptLine, error) {
+	lines := make([]ScriptLine, 0, 10)
+	scanner := bufio.NewScanner(r)
+	var (
+		pending strings.Builder
+		start   int
+		no      int
+	)
+	for scanner.Scan() {
+		no++
+		text := scanner.Text()
+		if pending.Len() == 0 {
+			trimed := strings.TrimSpace(text)
+			if len(trimed) == 0 || strings.HasPrefix(trimed, "#") {
+				continue
+			}
+			start = no
+		}
+		if continued(text) {
+			pending.WriteString(text[0 : len(text)-1])
+			continue
+		}
+		pending.WriteString(text)
+		lines = append(lines, ScriptLine{No: start, Input: strings.TrimSpace(pending.String())})
+		pending.Reset()
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if pending.Len() > 0 {
+		return nil, fmt.Errorf("第%d行续行后没有内容", start)
+	}
+	return lines, nil
+}
+
+// 行尾为未转义的\
+func continued(text string) bool {
+	n := 0
+	for i := len(text) - 1; i >= 0 && text[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// 逐条执行脚本指令,遇到错误时停止(continueOnErr为true时继续),返回执行记录及汇总
+func RunScript(ctx Context, lines []ScriptLine, continueOnErr bool) Message {
+	rctx := ctx
+	if pctx, ok := ctx.(*pluginContext); ok {
+		rctx = pctx.Context
+	}
+	r := rctx.(registreyContext).registry()
+	depth, _ := rctx.Value(script_depth).(int)
+	if depth >= maxScriptDepth {
+		return ErrMessage(0, "脚本嵌套超过%d层", maxScriptDepth)
+	}
+	rctx.SetValue(script_depth, depth+1)
+	defer rctx.SetValue(script_depth, depth)
+
+	var (
+		output  strings.Builder
+		summary strings.Builder
+		succ    int
+		failed  int
+		stopped bool
+	)
+	for _, line := range lines {
+		// 出错或被取消(中断信号,-timeout)后余下的指令跳过
+		if stopped || rctx.Err() != nil {
+			summary.WriteString(fmt.Sprintf("%4d %-4s %s\n", line.No, "skip", line.Input))
+			continue
+		}
+		msg, ok := runScriptLine(rctx, r, line.Input)
+		output.WriteString(fmt.Sprintf("> %s\n", line.Input))
+		if text := FormatMessage(msg); len(text) > 0 {
+			output.WriteString(strings.TrimRight(text, "\n"))
+			output.WriteString("\n")
+		}
+		status := "ok"
+		if ok {
+			succ++
+		} else {
+			failed++
+			status = "fail"
+			stopped = !continueOnErr
+		}
+		summary.WriteString(fmt.Sprintf("%4d %-4s %s\n", line.No, status, line.Input))
+		// quit 结束脚本
+		if msg != nil && msg.Code() < 0 {
+			stopped = true
+		}
+	}
+	output.WriteString("----\n")
+	output.WriteString(summary.String())
+	output.WriteString(fmt.Sprintf("共%d条: 成功%d,失败%d,跳过%d", len(lines), succ, failed, len(lines)-succ-failed))
+	if err := rctx.Err(); err != nil {
+		return WrapMessage(0, err, "%s", output.String())
+	}
+	if failed > 0 {
+		return ErrMessage(0, "%s", output.String())
+	}
+	return InfoMessage(0, "%s", output.String())
+}
+
+// ok为false表示指令无效或执行出错
+func runScriptLine(ctx Context, r Registry, input string) (msg Message, ok bool) {
+	tokens, msg := Tokenize(input)
+	if msg != nil {
+		return msg, false
+	}
+	c, args, fmap, msg := resolveInput(r, tokens)
+	if msg != nil {
+		return msg, false
+	}
+	msg = runCommand(ctx, c, args, fmap)
+	if msg == nil {
+		return nil, true
+	}
+	_, failed := msg.Err()
+	return msg, !failed || msg.Code() < 0
+}
+
+// 读取并执行脚本文件,相对路径基于工作目录
+func RunScriptFile(ctx Context, file string, continueOnErr bool) Message {
+	if !filepath.IsAbs(file) && len(ctx.WorkDir()) > 0 {
+		file = filepath.Join(ctx.WorkDir(), file)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return WrapMessage(0, err, "打开脚本失败").WithDetail("文件", file)
+	}
+	defer f.Close()
+	lines, err := ReadScript(f)
+	if err != nil {
+		return WrapMessage(0, err, "读取脚本失败").WithDetail("文件", file)
+	}
+	return RunScript(ctx, lines, continueOnErr)
+}
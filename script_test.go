@@ -0,0 +1,72 @@
+package gocli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadScript(t *testing.T) {
+	script := `# setup
+show plugins
+
+  # indented comment
+genplugin a.go \
+  -nm demo \
+  -ver v1.0.0
+echo "a \\" b
+`
+	lines, err := ReadScript(strings.NewReader(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []ScriptLine{
+		{No: 2, Input: "show plugins"},
+		{No: 5, Input: "genplugin a.go   -nm demo   -ver v1.0.0"},
+		{No: 8, Input: `echo "a \\" b`},
+	}
+	if !reflect.DeepEqual(lines, expect) {
+		t.Errorf("expect %+v, got %+v", expect, lines)
+	}
+	if _, err := ReadScript(strings.NewReader("show \\")); err == nil {
+		t.Error("dangling continuation should fail")
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	fail := NewCommand("fail", "", func(ctx Context, args []string, flagmap FlagMap) Message {
+		return ErrMessage(0, "failed")
+	})
+	ctx := testContext(t, gogenCore, &GeneralPlugin{ID: "script_plugin", Commands: []Command{echoCommand("echo"), fail}})
+	lines := []ScriptLine{{1, "echo"}, {2, "fail"}, {4, "ehco"}, {5, "echo"}}
+
+	msg := RunScript(ctx, lines, false)
+	if _, failed := msg.Err(); !failed {
+		t.Fatalf("expect failure, got %s", msg.Msg())
+	}
+	for _, expect := range []string{"> echo\necho\n", "   2 fail fail", "   4 skip ehco", "成功1,失败1,跳过2"} {
+		if !strings.Contains(msg.Msg(), expect) {
+			t.Errorf("expect %q in:\n%s", expect, msg.Msg())
+		}
+	}
+
+	msg = RunScript(ctx, lines, true)
+	if !strings.Contains(msg.Msg(), "成功2,失败2,跳过0") || !strings.Contains(msg.Msg(), "`echo`") {
+		t.Errorf("continue on error:\n%s", msg.Msg())
+	}
+
+	file := filepath.Join(ctx.WorkDir(), "nested.gocli")
+	if err := os.WriteFile(file, []byte("echo\nsource nested.gocli\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	msg = RunScriptFile(ctx, "nested.gocli", false)
+	if !strings.Contains(msg.Msg(), "脚本嵌套超过") {
+		t.Errorf("expect nesting limit:\n%s", msg.Msg())
+	}
+	msg = RunScript(ctx, []ScriptLine{{1, "echo"}, {2, "quit"}, {3, "echo"}}, false)
+	if _, failed := msg.Err(); failed || !strings.Contains(msg.Msg(), "   3 skip echo") {
+		t.Errorf("quit should stop script:\n%s", msg.Msg())
+	}
+}
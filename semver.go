@@ -0,0 +1,372 @@
+package gocli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 语义化版本 major.minor.patch[-prerelease][+build],可带前缀v,缺省的minor,patch为0
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	Pre   []string
+	Build string
+}
+
+func ParseVersion(s string) (Version, error) {
+	v, _, wild, err := parsePartial(s)
+	if err == nil && wild {
+		err = fmt.Errorf("无效的版本:%q", s)
+	}
+	return v, err
+}
+
+// 解析可能不完整的版本(1,1.2,1.x,*),parts为给出的数字个数,wild表示含x或*
+func parsePartial(s string) (v Version, parts int, wild bool, err error) {
+	text := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	invalid := fmt.Errorf("无效的版本:%q", s)
+	if pos := strings.Index(text, "+"); pos >= 0 {
+		v.Build = text[pos+1:]
+		text = text[:pos]
+		if !validIdentifiers(v.Build, false) {
+			return v, 0, false, invalid
+		}
+	}
+	if pos := strings.Index(text, "-"); pos >= 0 {
+		pre := text[pos+1:]
+		text = text[:pos]
+		if !validIdentifiers(pre, true) {
+			return v, 0, false, invalid
+		}
+		v.Pre = strings.Split(pre, ".")
+	}
+	fields := strings.Split(text, ".")
+	if len(text) == 0 || len(fields) > 3 {
+		return v, 0, false, invalid
+	}
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i := range fields {
+		if isWildcard(fields[i]) {
+			wild = true
+			continue
+		}
+		if wild {
+			// x之后不能再有数字,如 1.x.3
+			return v, 0, false, invalid
+		}
+		n, err := strconv.Atoi(fields[i])
+		if err != nil || n < 0 || (len(fields[i]) > 1 && fields[i][0] == '0') {
+			return v, 0, false, invalid
+		}
+		*nums[i] = n
+		parts++
+	}
+	if parts == 0 && len(v.Pre) > 0 {
+		return v, 0, false, invalid
+	}
+	return v, parts, wild, nil
+}
+
+func isWildcard(s string) bool {
+	return s == "x" || s == "X" || s == "*"
+}
+
+// 点分隔的标识符,只允许字母,数字及-;prerelease中的数字不能有前导0
+func validIdentifiers(s string, pre bool) bool {
+	for _, id := range strings.Split(s, ".") {
+		if len(id) == 0 {
+			return false
+		}
+		numeric := true
+		for _, r := range id {
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-') {
+				return false
+			}
+			numeric = numeric && r >= '0' && r <= '9'
+		}
+		if pre && numeric && len(id) > 1 && id[0] == '0' {
+			return false
+		}
+	}
+	return true
+}
+
+// 按语义化版本的优先级比较,忽略build;小于返回-1,等于0,大于1
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	// 有prerelease的版本低于正式版本
+	switch {
+	case len(v.Pre) == 0 && len(o.Pre) == 0:
+		return 0
+	case len(v.Pre) == 0:
+		return 1
+	case len(o.Pre) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.Pre) && i < len(o.Pre); i++ {
+		if d := compareIdentifier(v.Pre[i], o.Pre[i]); d != 0 {
+			return d
+		}
+	}
+	return sign(len(v.Pre) - len(o.Pre))
+}
+
+// 数字标识符按数值比较,且低于字母标识符
+func compareIdentifier(a string, b string) int {
+	na, aerr := strconv.Atoi(a)
+	nb, berr := strconv.Atoi(b)
+	switch {
+	case aerr == nil && berr == nil:
+		return sign(na - nb)
+	case aerr == nil:
+		return -1
+	case berr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func sign(d int) int {
+	if d < 0 {
+		return -1
+	}
+	if d > 0 {
+		return 1
+	}
+	return 0
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Pre) > 0 {
+		s += "-" + strings.Join(v.Pre, ".")
+	}
+	if len(v.Build) > 0 {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// 与v的major.minor.patch相同
+func (v Version) sameCore(o Version) bool {
+	return v.Major == o.Major && v.Minor == o.Minor && v.Patch == o.Patch
+}
+
+// 版本约束,满足任一范围(||分隔)即可,范围内的条件(空格或逗号分隔)需全部满足:
+//
+//	>=1.2.0 <2.0.0    比较 = > >= < <=
+//	^1.2.3            >=1.2.3 <2.0.0 (^0.2.3 为 >=0.2.3 <0.3.0)
+//	~1.2.3            >=1.2.3 <1.3.0
+//	1.2.x, 1.2, 1     x-range,如 1.2 为 >=1.2.0 <1.3.0
+//	1.2.0 - 1.4       >=1.2.0 <1.5.0
+//	* 或空            不限
+//
+// 带prerelease的版本只匹配同一major.minor.patch且带prerelease的条件,如 >=1.2.0-beta
+type Constraint struct {
+	ranges [][]comparator
+	text   string
+}
+
+type comparator struct {
+	op      string
+	version Version
+}
+
+func (c comparator) check(v Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	}
+	return cmp == 0
+}
+
+var constraintOps = []string{">=", "<=", "==", ">", "<", "=", "^", "~"}
+
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{text: strings.TrimSpace(s)}
+	parts := strings.Split(c.text, "||")
+	for _, part := range parts {
+		// 空的范围匹配所有版本,只允许整个约束为空
+		if len(parts) > 1 && len(rangeTokens(part)) == 0 {
+			return c, fmt.Errorf("无效的版本约束:%q,||两侧不能为空", s)
+		}
+		set, err := parseRange(part)
+		if err != nil {
+			return c, fmt.Errorf("无效的版本约束:%q,%s", s, err.Error())
+		}
+		c.ranges = append(c.ranges, set)
+	}
+	return c, nil
+}
+
+func rangeTokens(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return r == ' ' || r == ',' || r == '\t'
+	})
+}
+
+func parseRange(text string) ([]comparator, error) {
+	tokens := rangeTokens(text)
+	set := make([]comparator, 0, 2)
+	for i := 0; i < len(tokens); i++ {
+		// 1.2.0 - 1.4
+		if i+2 < len(tokens) && tokens[i+1] == "-" {
+			lower, err := parseComparator(">=" + tokens[i])
+			if err != nil {
+				return nil, err
+			}
+			upper, err := parseComparator("<=" + tokens[i+2])
+			if err != nil {
+				return nil, err
+			}
+			set = append(set, lower...)
+			set = append(set, upper...)
+			i += 2
+			continue
+		}
+		// >= 1.2.0
+		tok := tokens[i]
+		if isOperator(tok) && i+1 < len(tokens) {
+			tok += tokens[i+1]
+			i++
+		}
+		cs, err := parseComparator(tok)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, cs...)
+	}
+	return set, nil
+}
+
+func isOperator(tok string) bool {
+	for _, op := range constraintOps {
+		if tok == op {
+			return true
+		}
+	}
+	return false
+}
+
+// 展开为基本的比较条件,不完整的版本按x-range处理
+func parseComparator(tok string) ([]comparator, error) {
+	op := ""
+	for _, o := range constraintOps {
+		if strings.HasPrefix(tok, o) {
+			op = o
+			tok = tok[len(o):]
+			break
+		}
+	}
+	v, parts, _, err := parsePartial(tok)
+	if err != nil {
+		return nil, err
+	}
+	// 不完整版本的下一个版本,如 1.2 => 1.3.0
+	next := func(parts int) Version {
+		switch parts {
+		case 1:
+			return Version{Major: v.Major + 1}
+		case 2:
+			return Version{Major: v.Major, Minor: v.Minor + 1}
+		}
+		return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+	}
+	switch op {
+	case "^":
+		upper := Version{Major: v.Major + 1}
+		switch {
+		case v.Major == 0 && parts >= 2 && (v.Minor > 0 || parts == 2):
+			upper = Version{Minor: v.Minor + 1}
+		case v.Major == 0 && parts == 3:
+			upper = Version{Patch: v.Patch + 1}
+		case parts == 0:
+			return nil, nil
+		}
+		return []comparator{{">=", v}, {"<", upper}}, nil
+	case "~":
+		if parts == 0 {
+			return nil, nil
+		}
+		return []comparator{{">=", v}, {"<", next(minInt(parts, 2))}}, nil
+	case ">":
+		if parts == 0 {
+			return []comparator{{"<", Version{}}}, nil
+		}
+		if parts < 3 {
+			return []comparator{{">=", next(parts)}}, nil
+		}
+	case "<=":
+		if parts == 0 {
+			return nil, nil
+		}
+		if parts < 3 {
+			return []comparator{{"<", next(parts)}}, nil
+		}
+	case ">=", "<":
+		if parts == 0 {
+			if op == "<" {
+				return []comparator{{"<", Version{}}}, nil
+			}
+			return nil, nil
+		}
+	default:
+		op = "="
+		if parts == 0 {
+			return nil, nil
+		}
+		if parts < 3 {
+			return []comparator{{">=", v}, {"<", next(parts)}}, nil
+		}
+	}
+	return []comparator{{op, v}}, nil
+}
+
+func (c Constraint) Check(v Version) bool {
+	if len(c.ranges) == 0 {
+		return true
+	}
+	for _, set := range c.ranges {
+		if satisfies(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func satisfies(set []comparator, v Version) bool {
+	for _, c := range set {
+		if !c.check(v) {
+			return false
+		}
+	}
+	if len(v.Pre) == 0 {
+		return true
+	}
+	for _, c := range set {
+		if len(c.version.Pre) > 0 && c.version.sameCore(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Constraint) String() string {
+	if len(c.text) == 0 {
+		return "*"
+	}
+	return c.text
+}
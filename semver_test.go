@@ -0,0 +1,101 @@
+package gocli
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	cases := map[string]string{
+		"v0.0.1":                "0.0.1",
+		"1.2.3":                 "1.2.3",
+		"2.1":                   "2.1.0",
+		" 3 ":                   "3.0.0",
+		"1.0.0-alpha.1":         "1.0.0-alpha.1",
+		"v1.0.0-rc.1+build.5":   "1.0.0-rc.1+build.5",
+		"1.2.3+20230101.sha-ab": "1.2.3+20230101.sha-ab",
+	}
+	for input, expect := range cases {
+		if v, err := ParseVersion(input); err != nil || v.String() != expect {
+			t.Errorf("%q: expect %s, got %v %v", input, expect, v, err)
+		}
+	}
+	for _, input := range []string{"", "unkown", "1.2.3.4", "1.-2", "v", "01.2.3", "1.x", "*", "1.0.0-", "1.0.0-01", "1.0.0+a_b"} {
+		if _, err := ParseVersion(input); err == nil {
+			t.Errorf("%q should be invalid", input)
+		}
+	}
+}
+
+func TestCompareVersion(t *testing.T) {
+	// 按优先级从低到高
+	ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.10.0", "2.0.0"}
+	for i := 1; i < len(ordered); i++ {
+		a, _ := ParseVersion(ordered[i-1])
+		b, _ := ParseVersion(ordered[i])
+		if a.Compare(b) != -1 || b.Compare(a) != 1 {
+			t.Errorf("expect %s < %s", a, b)
+		}
+	}
+	a, _ := ParseVersion("1.0.0+build.1")
+	b, _ := ParseVersion("1.0.0+build.2")
+	if a.Compare(b) != 0 {
+		t.Error("build metadata should be ignored")
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	cases := []struct {
+		constraint string
+		version    string
+		ok         bool
+	}{
+		{">=1.2.0", "1.2.0", true},
+		{">=1.2.0", "1.10.0", true},
+		{">=1.2.0", "1.1.9", false},
+		{">= 1.2.0, <2", "1.9.9", true},
+		{">=1.2.0 <2", "2.0.0", false},
+		{">1.2", "1.2.9", false},
+		{">1.2", "1.3.0", true},
+		{"<=2", "2.9.0", true},
+		{"<=2", "3.0.0", false},
+		{"1.2.0", "v1.2.0", true},
+		{"==1.2.0", "1.2.1", false},
+		{"1.2", "1.2.7", true},
+		{"1.2.x", "1.3.0", false},
+		{"1", "1.9.0", true},
+		{"^1.2.3", "1.9.0", true},
+		{"^1.2.3", "2.0.0", false},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"^0.0.3", "0.0.4", false},
+		{"~1.2.3", "1.2.9", true},
+		{"~1.2.3", "1.3.0", false},
+		{"~1", "1.5.0", true},
+		{"1.2.0 - 1.4", "1.4.9", true},
+		{"1.2.0 - 1.4", "1.5.0", false},
+		{"<1 || >=2.1", "0.5.0", true},
+		{"<1 || >=2.1", "1.5.0", false},
+		{"<1 || >=2.1", "3.0.0", true},
+		{">=1.0.0", "1.1.0-beta", false},
+		{">=1.1.0-alpha", "1.1.0-beta", true},
+		{">=1.1.0-alpha", "1.2.0-beta", false},
+		{"*", "0.0.1", true},
+		{"", "9.9.9", true},
+	}
+	for _, c := range cases {
+		constraint, err := ParseConstraint(c.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := ParseVersion(c.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if constraint.Check(v) != c.ok {
+			t.Errorf("%s %s: expect %v", c.constraint, c.version, c.ok)
+		}
+	}
+	for _, input := range []string{">=a", "^1.2.3.4", "1.2 || >=x.1", ">=2 ||", "|| 1.0", "1.0 || || 2.0", "1.0 || ,"} {
+		if _, err := ParseConstraint(input); err == nil {
+			t.Errorf("%q should be invalid", input)
+		}
+	}
+}
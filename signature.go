@@ -0,0 +1,247 @@
+package gocli
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 插件签名:对.so文件的SHA-256摘要做Ed25519签名,写在同目录的 {file}.sig 中,
+// 加载时用可信公钥目录(-trust)中的 *.pub 验证
+const (
+	signatureSuffix  = ".sig"
+	publicKeySuffix  = ".pub"
+	privateKeySuffix = ".key"
+)
+
+var (
+	ErrUnsigned     = errors.New("插件未签名")
+	ErrUntrusted    = errors.New("签名公钥不在可信列表中")
+	ErrBadSignature = errors.New("签名校验失败")
+	ErrDigest       = errors.New("文件摘要不一致")
+)
+
+// sidecar签名文件内容
+type PluginSignature struct {
+	Algorithm algorithm `json:"algorithm"`
+	// 公钥指纹
+	Key string `json:"key"`
+	// sha256:{hex}
+	Digest    string `json:"digest"`
+	Signature string `json:"signature"`
+}
+
+type TrustedKey struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+	File string `json:"file"`
+	key  ed25519.PublicKey
+}
+
+// 可信公钥,按指纹索引
+type TrustStore struct {
+	Dir  string
+	keys map[string]*TrustedKey
+}
+
+// 公钥指纹,sha256的前8个字节
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// 默认可信公钥目录 ~/.gocli/trust
+func DefaultTrustDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".gocli/trust"
+	}
+	return filepath.Join(home, ".gocli", "trust")
+}
+
+// 读取dir下的 *.pub,文件名即公钥名称;目录不存在时为空
+func LoadTrustStore(dir string) (*TrustStore, error) {
+	store := &TrustStore{Dir: dir, keys: make(map[string]*TrustedKey)}
+	files, err := filepath.Glob(filepath.Join(dir, "*"+publicKeySuffix))
+	if err != nil {
+		return store, err
+	}
+	problems := make([]string, 0)
+	for _, file := range files {
+		pub, err := readPublicKey(file)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s:%s", filepath.Base(file), err.Error()))
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(file), publicKeySuffix)
+		store.Add(name, pub).File = file
+	}
+	if len(problems) > 0 {
+		return store, fmt.Errorf("无效的公钥: %s", strings.Join(problems, ", "))
+	}
+	return store, nil
+}
+
+func (store *TrustStore) Add(name string, pub ed25519.PublicKey) *TrustedKey {
+	key := &TrustedKey{Name: name, ID: KeyID(pub), key: pub}
+	store.keys[key.ID] = key
+	return key
+}
+
+func (store *TrustStore) Lookup(id string) (*TrustedKey, bool) {
+	if store == nil {
+		return nil, false
+	}
+	key, ok := store.keys[id]
+	return key, ok
+}
+
+// 按名称排序
+func (store *TrustStore) Keys() []*TrustedKey {
+	keys := make([]*TrustedKey, 0)
+	if store == nil {
+		return keys
+	}
+	for _, key := range store.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Name == keys[j].Name {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].Name < keys[j].Name
+	})
+	return keys
+}
+
+// 生成密钥对,写入 dir/{name}.key(私钥,0600) 及 dir/{name}.pub
+func GenerateKeyPair(dir string, name string) (ed25519.PublicKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	keyFile := filepath.Join(dir, name+privateKeySuffix)
+	if _, err := os.Stat(keyFile); err == nil {
+		return nil, fmt.Errorf("私钥已存在:%s", keyFile)
+	}
+	if err := os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(priv)+"\n"), 0600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+publicKeySuffix), []byte(base64.StdEncoding.EncodeToString(pub)+"\n"), 0644); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+func readPublicKey(file string) (ed25519.PublicKey, error) {
+	bts, err := readKey(file, ed25519.PublicKeySize)
+	return ed25519.PublicKey(bts), err
+}
+
+func readPrivateKey(file string) (ed25519.PrivateKey, error) {
+	bts, err := readKey(file, ed25519.PrivateKeySize)
+	return ed25519.PrivateKey(bts), err
+}
+
+// base64编码的密钥
+func readKey(file string, size int) ([]byte, error) {
+	text, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	bts, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(text)))
+	if err != nil || len(bts) != size {
+		return nil, fmt.Errorf("密钥格式错误,需要base64编码的%d字节", size)
+	}
+	return bts, nil
+}
+
+func fileDigest(file string) ([]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+// 用私钥签名插件文件,写入 {file}.sig
+func SignPluginFile(file string, keyFile string) (*PluginSignature, error) {
+	priv, err := readPrivateKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := fileDigest(file)
+	if err != nil {
+		return nil, err
+	}
+	sig := &PluginSignature{
+		Algorithm: algorithm_ed25519,
+		Key:       KeyID(priv.Public().(ed25519.PublicKey)),
+		Digest:    string(algorithm_sha256) + ":" + hex.EncodeToString(digest),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(priv, digest)),
+	}
+	bts, _ := json.MarshalIndent(sig, "", "  ")
+	return sig, os.WriteFile(file+signatureSuffix, append(bts, '\n'), 0644)
+}
+
+// 计算插件文件的摘要并校验签名;返回的signature总带有摘要,
+// 未签名,公钥不可信或签名无效时 verified 为false,并返回原因
+func VerifyPluginFile(file string, store *TrustStore) (*signature, error) {
+	digest, err := fileDigest(file)
+	if err != nil {
+		return nil, err
+	}
+	return verifyDigest(file, digest, store)
+}
+
+func verifyDigest(file string, digest []byte, store *TrustStore) (*signature, error) {
+	sign := &signature{kind: algorithm_sha256, digest: hex.EncodeToString(digest)}
+	bts, err := os.ReadFile(file + signatureSuffix)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sign, ErrUnsigned
+		}
+		return sign, err
+	}
+	var sig PluginSignature
+	if err := json.Unmarshal(bts, &sig); err != nil {
+		return sign, fmt.Errorf("%w: %s", ErrBadSignature, err.Error())
+	}
+	if sig.Algorithm != algorithm_ed25519 {
+		return sign, fmt.Errorf("%w: 不支持的算法%q", ErrBadSignature, sig.Algorithm)
+	}
+	sign.kind = algorithm_ed25519
+	sign.key = sig.Key
+	if sig.Digest != string(algorithm_sha256)+":"+sign.digest {
+		return sign, ErrDigest
+	}
+	key, ok := store.Lookup(sig.Key)
+	if !ok {
+		return sign, fmt.Errorf("%w: %s", ErrUntrusted, sig.Key)
+	}
+	sign.signer = key.Name
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil || !ed25519.Verify(key.key, digest, raw) {
+		return sign, ErrBadSignature
+	}
+	sign.verified = true
+	return sign, nil
+}
@@ -0,0 +1,169 @@
+package gocli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSignPlugin(t *testing.T) {
+	dir := t.TempDir()
+	keys := filepath.Join(dir, "keys")
+	trust := filepath.Join(dir, "trust")
+	pub, err := GenerateKeyPair(keys, "release")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GenerateKeyPair(keys, "release"); err == nil {
+		t.Error("existing key should not be overwritten")
+	}
+	if info, _ := os.Stat(filepath.Join(keys, "release.key")); info == nil || info.Mode().Perm() != 0600 {
+		t.Error("private key should be 0600")
+	}
+	if err := os.MkdirAll(trust, 0700); err != nil {
+		t.Fatal(err)
+	}
+	bts, err := os.ReadFile(filepath.Join(keys, "release.pub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(trust, "release.pub"), bts, 0644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := LoadTrustStore(trust)
+	if err != nil || len(store.Keys()) != 1 || store.Keys()[0].ID != KeyID(pub) || store.Keys()[0].Name != "release" {
+		t.Fatalf("trust store: %v %+v", err, store.Keys())
+	}
+
+	so := filepath.Join(dir, "demo.so")
+	if err := os.WriteFile(so, []byte("plugin binary"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if sign, err := VerifyPluginFile(so, store); !errors.Is(err, ErrUnsigned) || sign.verified || len(sign.digest) != 64 {
+		t.Errorf("expect unsigned, got %v %+v", err, sign)
+	}
+	if _, err := SignPluginFile(so, filepath.Join(keys, "release.key")); err != nil {
+		t.Fatal(err)
+	}
+	sign, err := VerifyPluginFile(so, store)
+	if err != nil || !sign.verified || sign.signer != "release" || sign.kind != algorithm_ed25519 {
+		t.Fatalf("expect verified, got %v %+v", err, sign)
+	}
+
+	// 公钥不在可信目录
+	if _, err := VerifyPluginFile(so, &TrustStore{keys: map[string]*TrustedKey{}}); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("expect untrusted, got %v", err)
+	}
+	// 签名后文件被修改
+	if err := os.WriteFile(so, []byte("plugin binary, tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if sign, err := VerifyPluginFile(so, store); !errors.Is(err, ErrDigest) || sign.verified {
+		t.Errorf("expect digest mismatch, got %v", err)
+	}
+}
+
+func TestTrustStoreInvalidKey(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.pub"), []byte("not a key"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := LoadTrustStore(dir)
+	if err == nil || len(store.Keys()) != 0 {
+		t.Errorf("expect invalid key error, got %v", err)
+	}
+	if store, err := LoadTrustStore(filepath.Join(dir, "missing")); err != nil || len(store.Keys()) != 0 {
+		t.Errorf("missing dir should be empty, got %v", err)
+	}
+}
+
+func TestPluginKeysCommand(t *testing.T) {
+	dir := t.TempDir()
+	msg := CLI().Run([]string{"plugin", "keygen", "release", "-dir", dir})
+	if msg.Code() != 0 {
+		t.Fatal(FormatMessage(msg))
+	}
+	msg = CLI().Run([]string{"plugin", "keys", "-trust", dir})
+	data, _ := msg.(DataMessage)
+	if data == nil {
+		t.Fatalf("plugin keys: %s", FormatMessage(msg))
+	}
+	keys, ok := data.Data().([]*TrustedKey)
+	if msg.Code() != 0 || !ok || len(keys) != 1 || keys[0].Name != "release" {
+		t.Errorf("plugin keys: %s %+v", FormatMessage(msg), data.Data())
+	}
+}
+
+func TestLoadPluginVerifyBeforeOpen(t *testing.T) {
+	dir := t.TempDir()
+	keys := filepath.Join(dir, "keys")
+	plugins := filepath.Join(dir, "plugins")
+	if err := os.MkdirAll(plugins, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GenerateKeyPair(keys, "release"); err != nil {
+		t.Fatal(err)
+	}
+	store, err := LoadTrustStore(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	console, ok := NewConsole(nil, NewLogger(filepath.Join(dir, "test.log"))).Log()
+	if !ok {
+		t.Fatal("file logger unavailable")
+	}
+
+	// 不是有效的.so,打开即失败;用于区分校验阶段与打开阶段
+	unsigned := filepath.Join(plugins, "unsigned.so")
+	signed := filepath.Join(plugins, "signed.so")
+	if err := os.WriteFile(unsigned, []byte("unsigned"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(signed, []byte("signed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SignPluginFile(signed, filepath.Join(keys, "release.key")); err != nil {
+		t.Fatal(err)
+	}
+
+	report := LoadPlugin([]string{plugins, filepath.Join(dir, "missing")}, store, true, console)
+	if report.Found != 2 || len(report.Loaded) != 0 || len(report.Rejected) != 3 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	stages := map[string]LoadStage{}
+	for _, r := range report.Rejected {
+		stages[filepath.Base(r.File)] = r.Stage
+	}
+	if stages["missing"] != LoadScan || stages["unsigned.so"] != LoadVerify || stages["signed.so"] != LoadOpen {
+		t.Errorf("unexpected stages %v", stages)
+	}
+	for _, r := range report.Rejected {
+		if r.Stage == LoadVerify && (!errors.Is(r, ErrUnsigned) || len(r.Digest) != 64) {
+			t.Errorf("expect unsigned with digest, got %v %s", r, r.Digest)
+		}
+	}
+
+	// 不校验时未签名的插件也会被打开
+	report = LoadPlugin([]string{plugins}, store, false, console)
+	for _, r := range report.Rejected {
+		if r.Stage != LoadOpen {
+			t.Errorf("expect open stage without -check, got %v", r)
+		}
+	}
+	if len(report.Warnings) != 0 {
+		t.Errorf("valid or missing signatures should not warn, got %v", report.Warnings)
+	}
+
+	// 签名后被修改的插件,不校验时仍打开,但记录警告
+	if err := os.WriteFile(signed, []byte("signed, tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	report = LoadPlugin([]string{plugins}, store, false, console)
+	if len(report.Warnings) != 1 || !errors.Is(report.Warnings[0], ErrDigest) || filepath.Base(report.Warnings[0].File) != "signed.so" {
+		t.Fatalf("expect digest warning for signed.so, got %v", report.Warnings)
+	}
+	if len(report.Rejected) != 2 || report.Rejected[0].Stage != LoadOpen || report.Rejected[1].Stage != LoadOpen {
+		t.Errorf("tampered plugin should still be opened without -check, got %v", report.Rejected)
+	}
+}
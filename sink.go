@@ -0,0 +1,143 @@
+package gocli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// 指令执行过程中的输出,通过 Context.Output 获取:
+// 界面中实时显示在信息栏,命令行中写到标准输出
+type OutputSink interface {
+	// 输出一行
+	Println(text string, args ...any)
+	// 更新进度,Done 为true时结束该进度
+	Progress(p Progress)
+}
+
+// Total<=0 为不确定进度,按计数及转圈显示
+type Progress struct {
+	Title   string
+	Current int64
+	Total   int64
+	Done    bool
+}
+
+func (p Progress) Percent() int {
+	if p.Total <= 0 {
+		return -1
+	}
+	if p.Current >= p.Total {
+		return 100
+	}
+	return int(p.Current * 100 / p.Total)
+}
+
+var spinner = []string{"|", "/", "-", "\\"}
+
+const progressBarWidth = 20
+
+// 如 build [========>           ] 45% 45/100
+func (p Progress) Text(frame int) string {
+	var w strings.Builder
+	if len(p.Title) > 0 {
+		w.WriteString(p.Title)
+		w.WriteString(" ")
+	}
+	percent := p.Percent()
+	switch {
+	case percent >= 0:
+		filled := progressBarWidth * percent / 100
+		bar := strings.Repeat("=", filled)
+		if filled < progressBarWidth {
+			bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+		}
+		w.WriteString(fmt.Sprintf("[%s] %3d%% %d/%d", bar, percent, p.Current, p.Total))
+	case p.Done:
+		w.WriteString(fmt.Sprintf("完成 %d", p.Current))
+	default:
+		w.WriteString(fmt.Sprintf("%s %d", spinner[frame%len(spinner)], p.Current))
+	}
+	return w.String()
+}
+
+func (p Progress) String() string {
+	return p.Text(0)
+}
+
+// 写到w,终端中进度在同一行刷新,否则只输出结束的进度
+func NewWriterSink(w io.Writer) OutputSink {
+	tty := false
+	if f, ok := w.(*os.File); ok {
+		tty = term.IsTerminal(int(f.Fd())) && os.Getenv("TERM") != "dumb"
+	}
+	return &writerSink{w: w, tty: tty}
+}
+
+type writerSink struct {
+	mux    sync.Mutex
+	w      io.Writer
+	tty    bool
+	frame  int
+	inline bool
+}
+
+func (s *writerSink) Println(text string, args ...any) {
+	if len(args) > 0 {
+		text = fmt.Sprintf(text, args...)
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.inline {
+		io.WriteString(s.w, "\n")
+		s.inline = false
+	}
+	io.WriteString(s.w, strings.TrimRight(text, "\n")+"\n")
+}
+
+func (s *writerSink) Progress(p Progress) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if !s.tty {
+		if p.Done {
+			io.WriteString(s.w, p.Text(0)+"\n")
+		}
+		return
+	}
+	s.frame++
+	io.WriteString(s.w, "\r\033[K"+p.Text(s.frame))
+	s.inline = !p.Done
+	if p.Done {
+		io.WriteString(s.w, "\n")
+	}
+}
+
+var stdoutSink = NewWriterSink(os.Stdout)
+
+// 丢弃所有输出
+var DiscardSink OutputSink = discardSink{}
+
+type discardSink struct{}
+
+func (discardSink) Println(text string, args ...any) {}
+func (discardSink) Progress(p Progress)              {}
+
+// 按函数输出,进度只在结束时作为一行输出
+type lineSink func(line string)
+
+func (s lineSink) Println(text string, args ...any) {
+	if len(args) > 0 {
+		text = fmt.Sprintf(text, args...)
+	}
+	s(strings.TrimRight(text, "\n"))
+}
+
+func (s lineSink) Progress(p Progress) {
+	if p.Done {
+		s(p.Text(0))
+	}
+}
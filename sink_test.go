@@ -0,0 +1,73 @@
+package gocli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestProgressText(t *testing.T) {
+	cases := map[string]Progress{
+		"build [=========>          ]  45% 45/100": {Title: "build", Current: 45, Total: 100},
+		"build [====================] 100% 3/3":    {Title: "build", Current: 3, Total: 3, Done: true},
+		"download / 12":                            {Title: "download", Current: 12},
+		"download 完成 12":                           {Title: "download", Current: 12, Done: true},
+	}
+	for expect, p := range cases {
+		if text := p.Text(1); text != expect {
+			t.Errorf("expect %q, got %q", expect, text)
+		}
+	}
+}
+
+func TestWriterSink(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewWriterSink(&out)
+	sink.Println("step %d", 1)
+	sink.Progress(Progress{Title: "copy", Current: 1, Total: 2})
+	sink.Progress(Progress{Title: "copy", Current: 2, Total: 2, Done: true})
+	sink.Println("done\n")
+	expect := "step 1\ncopy [====================] 100% 2/2\ndone\n"
+	if out.String() != expect {
+		t.Errorf("expect %q, got %q", expect, out.String())
+	}
+	if ctx := testContext(t); ctx.Output() != stdoutSink {
+		t.Error("default sink should write to stdout")
+	}
+}
+
+func streamingCommand(key string) Command {
+	return NewCommand(key, key, func(ctx Context, args []string, flagmap FlagMap) Message {
+		out := ctx.Output()
+		out.Println("compiling")
+		for i := int64(1); i <= 2; i++ {
+			out.Progress(Progress{Title: key, Current: i, Total: 2, Done: i == 2})
+		}
+		return InfoMessage(0, "built")
+	})
+}
+
+func TestStreamOutput(t *testing.T) {
+	ctx := testContext(t, gogenCore, &GeneralPlugin{ID: "build_plugin", Commands: []Command{streamingCommand("build")}})
+
+	var out bytes.Buffer
+	RunStream(ctx, strings.NewReader("build\n"), &out, FrameText)
+	expect := ">>> 1 build\ncompiling\nbuild [====================] 100% 2/2\nbuilt\n<<< 1 ok\n"
+	if out.String() != expect {
+		t.Errorf("expect:\n%s\ngot:\n%s", expect, out.String())
+	}
+	if ctx.Output() != stdoutSink {
+		t.Error("stream sink should be removed after run")
+	}
+
+	out.Reset()
+	RunStream(ctx, strings.NewReader("build\n"), &out, FrameJson)
+	var f streamFrame
+	if err := json.NewDecoder(&out).Decode(&f); err != nil {
+		t.Fatal(err)
+	}
+	if f.Output != "compiling\nbuild [====================] 100% 2/2\nbuilt" {
+		t.Errorf("unexpected frame %+v", f)
+	}
+}
@@ -0,0 +1,124 @@
+package gocli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	FrameText = "text"
+	FrameJson = "json"
+)
+
+// json格式输出时,每条指令一行
+type streamFrame struct {
+	Seq    int    `json:"seq"`
+	Input  string `json:"input"`
+	OK     bool   `json:"ok"`
+	Code   int    `json:"code"`
+	Kind   string `json:"kind,omitempty"`
+	Output string `json:"output"`
+}
+
+type streamSummary struct {
+	Total  int `json:"total"`
+	Succ   int `json:"succeeded"`
+	Failed int `json:"failed"`
+}
+
+// 标准输入不是终端(管道,重定向或协作进程)
+func stdinPiped() bool {
+	return !term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// 逐行读取指令并执行,每条指令的结果按frame(text|json)格式立即写出;
+// 空行及#开头的行忽略,quit结束读取;有指令失败时返回错误信息
+func RunStream(ctx Context, in io.Reader, out io.Writer, frame string) Message {
+	rctx := ctx
+	if pctx, ok := ctx.(*pluginContext); ok {
+		rctx = pctx.Context
+	}
+	r := rctx.(registreyContext).registry()
+	var (
+		summary streamSummary
+		encoder = json.NewEncoder(out)
+		scanner = bufio.NewScanner(in)
+	)
+	encoder.SetEscapeHTML(false)
+	for rctx.Err() == nil && scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		summary.Total++
+		f := streamFrame{Seq: summary.Total, Input: line}
+		// 指令执行中的输出:text格式立即写出,json格式并入该条的output
+		var streamed []string
+		if frame == FrameJson {
+			rctx.SetValue(output_sink, lineSink(func(text string) { streamed = append(streamed, text) }))
+		} else {
+			fmt.Fprintf(out, ">>> %d %s\n", f.Seq, f.Input)
+			rctx.SetValue(output_sink, lineSink(func(text string) { fmt.Fprintln(out, text) }))
+		}
+		msg, ok := runScriptLine(rctx, r, line)
+		rctx.Remove(output_sink)
+		if ok {
+			summary.Succ++
+		} else {
+			summary.Failed++
+		}
+		f.OK = ok
+		if msg != nil {
+			f.Code, f.Kind, f.Output = msg.Code(), levelMap[msg.Kind()], FormatMessage(msg)
+		}
+		if frame == FrameJson {
+			if len(streamed) > 0 {
+				f.Output = strings.Join(append(streamed, f.Output), "\n")
+			}
+			encoder.Encode(f)
+		} else {
+			writeTextFrame(out, f)
+		}
+		if msg != nil && msg.Code() < 0 {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ErrMessage(0, "读取输入失败:%s", err.Error())
+	}
+	text := fmt.Sprintf("共%d条: 成功%d,失败%d", summary.Total, summary.Succ, summary.Failed)
+	if frame == FrameJson {
+		b, _ := json.Marshal(summary)
+		text = string(b)
+	}
+	if err := rctx.Err(); err != nil {
+		return WrapMessage(0, err, "%s", text)
+	}
+	if summary.Failed > 0 {
+		return ErrMessage(0, "%s", text)
+	}
+	return InfoMessage(0, "%s", text)
+}
+
+// >>> 序号 指令 (执行前写出)
+// 输出
+// <<< 序号 ok|fail
+func writeTextFrame(out io.Writer, f streamFrame) {
+	status := "ok"
+	if !f.OK {
+		status = "fail"
+	}
+	var w strings.Builder
+	if len(f.Output) > 0 {
+		w.WriteString(strings.TrimRight(f.Output, "\n"))
+		w.WriteString("\n")
+	}
+	w.WriteString(fmt.Sprintf("<<< %d %s\n", f.Seq, status))
+	io.WriteString(out, w.String())
+}
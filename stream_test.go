@@ -0,0 +1,41 @@
+package gocli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRunStream(t *testing.T) {
+	ctx := testContext(t, gogenCore, &GeneralPlugin{ID: "stream_plugin", Commands: []Command{echoCommand("echo")}})
+	input := "echo\n\n# comment\nnothing\nquit\necho\n"
+
+	var out bytes.Buffer
+	msg := RunStream(ctx, strings.NewReader(input), &out, FrameText)
+	if _, failed := msg.Err(); !failed || msg.Msg() != "共3条: 成功2,失败1" {
+		t.Errorf("unexpected summary %+v", msg)
+	}
+	expect := ">>> 1 echo\necho\n<<< 1 ok\n>>> 2 nothing\ncommand not found\n<<< 2 fail\n>>> 3 quit\n退出程序\n<<< 3 ok\n"
+	if out.String() != expect {
+		t.Errorf("expect:\n%s\ngot:\n%s", expect, out.String())
+	}
+
+	out.Reset()
+	msg = RunStream(ctx, strings.NewReader("echo\nnothing\n"), &out, FrameJson)
+	frames := make([]streamFrame, 0, 2)
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var f streamFrame
+		if err := decoder.Decode(&f); err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, f)
+	}
+	if len(frames) != 2 || !frames[0].OK || frames[0].Output != "echo" || frames[1].OK || frames[1].Code != 404 {
+		t.Errorf("unexpected frames %+v", frames)
+	}
+	if msg.Msg() != `{"total":2,"succeeded":1,"failed":1}` {
+		t.Errorf("unexpected summary %s", msg.Msg())
+	}
+}
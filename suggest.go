@@ -0,0 +1,190 @@
+package gocli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 编辑距离(按rune计算),相邻字符交换算一次编辑
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// 返回与input最接近的候选项,距离超过阈值的忽略
+func Suggest(input string, candidates []string) []string {
+	limit := len([]rune(input)) / 3
+	if limit < 1 {
+		limit = 1
+	}
+	if limit > 3 {
+		limit = 3
+	}
+	best := limit + 1
+	found := make([]string, 0, 3)
+	for _, c := range candidates {
+		d := editDistance(input, c)
+		if d == 0 || d > limit {
+			continue
+		}
+		if d < best {
+			best = d
+			found = found[:0]
+		}
+		if d == best {
+			found = append(found, c)
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// 按候选指令的层级截取输入后比较
+func suggestCommands(registry Registry, args []string) []string {
+	byLen := make(map[int][]string, 3)
+	for _, key := range registry.CommandKeys() {
+		n := len(strings.Fields(key))
+		if n > len(args) {
+			n = len(args)
+		}
+		byLen[n] = append(byLen[n], key)
+	}
+	found := make([]string, 0, 3)
+	best := -1
+	for n, keys := range byLen {
+		input := strings.Join(args[0:n], " ")
+		for _, s := range Suggest(input, keys) {
+			d := editDistance(input, s)
+			if best < 0 || d < best {
+				best = d
+				found = found[:0]
+			}
+			if d == best {
+				found = append(found, s)
+			}
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+func didYouMean(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(suggestions))
+	for i := range suggestions {
+		quoted[i] = fmt.Sprintf("`%s`", suggestions[i])
+	}
+	return fmt.Sprintf("did you mean %s?", strings.Join(quoted, " or "))
+}
+
+func notFoundMessage(registry Registry, args []string) Message {
+	msg := Rich(WarnMessage(CodeNotFound, "command not found"))
+	if tips := didYouMean(suggestCommands(registry, args)); len(tips) > 0 {
+		msg.WithHint(tips)
+	}
+	return msg
+}
+
+func flagNames(flags []Flag) []string {
+	names := make([]string, 0, len(flags)*2)
+	for i := range flags {
+		names = append(names, flags[i].Name())
+		if alias, ok := flags[i].Alias(); ok {
+			names = append(names, alias)
+		}
+	}
+	return names
+}
+
+// 输入中未声明的flag
+func unknownFlags(flags []Flag, fm FlagMap) []string {
+	declared := make(map[string]bool, len(flags)*2)
+	for _, name := range flagNames(flags) {
+		declared[name] = true
+	}
+	unknown := make([]string, 0, 2)
+	for _, key := range fm.keys() {
+		if !declared[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// 严格模式下拒绝未声明的flag,列出可用的flag
+func strictFlagsCheck(flags []Flag, fm FlagMap) Message {
+	unknown := unknownFlags(flags, fm)
+	if len(unknown) == 0 {
+		return nil
+	}
+	msg := Rich(ErrMessage(CodeUsage, "未知的flag:%s", strings.Join(unknown, ",")))
+	msg.WithDetail("可用的flag", strings.Join(flagNames(flags), ","))
+	return msg.WithHint(flagSuggestions(flags, fm))
+}
+
+// 输入中未声明的flag与声明的flag相近时的提示,如 -frmat: did you mean `-frame`?
+func flagSuggestions(flags []Flag, fm FlagMap) string {
+	names := flagNames(flags)
+	unknown := unknownFlags(flags, fm)
+	hints := make([]string, 0, len(unknown))
+	for i := range unknown {
+		if tips := didYouMean(Suggest(unknown[i], names)); len(tips) > 0 {
+			hints = append(hints, fmt.Sprintf("%s: %s", unknown[i], tips))
+		}
+	}
+	return strings.Join(hints, "; ")
+}
+
+// 非严格模式下未声明的flag被忽略,返回附带相近flag提示的新Message,不修改原结果
+func withFlagHint(msg Message, hint string) Message {
+	if len(hint) == 0 || msg == nil {
+		return msg
+	}
+	if dm, ok := msg.(*dataMessage); ok {
+		return &dataMessage{Message: withFlagHint(dm.Message, hint), data: dm.data}
+	}
+	rm, ok := msg.(*RichMessage)
+	if ok {
+		rm = rm.clone()
+	} else {
+		rm = Rich(msg)
+	}
+	if len(rm.hint) > 0 {
+		hint = rm.hint + "; " + hint
+	}
+	return rm.WithHint(hint)
+}
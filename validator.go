@@ -63,7 +63,7 @@ func NewInputValidRule(rule InputValidRule, fail Message) InputValidator {
 
 func (iv *inputValidRule) Valid(ctx Context, value *CommandInputs) (bool, Message) {
 	if value == nil {
-		return false, ErrMessage(0, "无效的输入")
+		return false, ErrMessage(CodeUsage, "无效的输入")
 	}
 	ok, msg := iv.rule(ctx, value.Args, value.Flags)
 	return ok, FirstNoneNilResult(msg, iv.fail)
@@ -74,7 +74,7 @@ type InputValidRule = func(ctx Context, args []string, flags FlagMap) (bool, Mes
 func EmptyArgs() Validator[*CommandInputs] {
 	return NewInputValidRule(func(ctx Context, args []string, flags FlagMap) (bool, Message) {
 		if len(args) > 0 {
-			return false, ErrMessage(0, "该指令不带参数")
+			return false, ErrMessage(CodeUsage, "该指令不带参数")
 		}
 		return true, nil
 	}, nil)
@@ -86,7 +86,7 @@ func ExactlyLength(size uint, emsg Message) Validator[*CommandInputs] {
 		if !pass {
 			msg = emsg
 			if msg == nil {
-				msg = ErrMessage(0, fmt.Sprintf("参数个数不匹配,期望:%d,输入:%d", size, len(args)))
+				msg = ErrMessage(CodeUsage, fmt.Sprintf("参数个数不匹配,期望:%d,输入:%d", size, len(args)))
 			}
 		}
 		return
@@ -101,13 +101,13 @@ func ExpectLength(min int, max int, emsg Message) Validator[*CommandInputs] {
 			if emsg != nil {
 				return false, emsg
 			}
-			return false, ErrMessage(0, fmt.Sprintf("参数个数不匹配:至少%d,输入:%d", min, inlen))
+			return false, ErrMessage(CodeUsage, fmt.Sprintf("参数个数不匹配:至少%d,输入:%d", min, inlen))
 		}
 		if max > 0 && inlen > max {
 			if emsg != nil {
 				return false, emsg
 			}
-			return false, ErrMessage(0, fmt.Sprintf("参数个数不匹配:至多%d,输入:%d", max, inlen))
+			return false, ErrMessage(CodeUsage, fmt.Sprintf("参数个数不匹配:至多%d,输入:%d", max, inlen))
 		}
 		return true, nil
 	}
@@ -134,7 +134,7 @@ func MustFlagged(flags ...*FlagExpected) Validator[*CommandInputs] {
 			emsg := expected.ErrMsg
 			if expected.Required && !ok {
 				if emsg == nil {
-					emsg = ErrMessage(0, fmt.Sprintf("Flag缺失,需要:%s", expected.Flag.Name()))
+					emsg = ErrMessage(CodeUsage, fmt.Sprintf("Flag缺失,需要:%s", expected.Flag.Name()))
 				}
 				return false, emsg
 			}
@@ -145,7 +145,7 @@ func MustFlagged(flags ...*FlagExpected) Validator[*CommandInputs] {
 				if ok, msg := expected.Validator.Valid(ctx, &CommandInputs{Args: f, Flags: fmap}); !ok {
 					emsg = msg
 					if emsg == nil {
-						emsg = ErrMessage(0, fmt.Sprintf("Flag%s不符合要求", expected.Flag.Name()))
+						emsg = ErrMessage(CodeInvalid, fmt.Sprintf("Flag%s不符合要求", expected.Flag.Name()))
 					}
 					return false, emsg
 				}
@@ -3,6 +3,7 @@ package gocli
 import (
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
@@ -154,6 +155,8 @@ type cliui struct {
 	histories  [][]string
 	maxHistory int
 	goindex    int
+	completing bool
+	job        *job
 	// mux       sync.Mutex
 }
 
@@ -166,6 +169,8 @@ func (ui *cliui) Run(prompt string, ctx Context) Message {
 	ctx.SetValueIfAbsent(history_list, &ui.histories)
 	ui.context = ctx
 	ui.logger = log.NewLogger(history_log)
+	ctx.SetValue(output_sink, &consoleSink{ui: ui})
+	defer ctx.Remove(output_sink)
 	app := tview.NewApplication()
 	ui.window = app
 
@@ -186,9 +191,22 @@ func (ui *cliui) Run(prompt string, ctx Context) Message {
 		return nil
 	})
 
+	// 收到中断信号时退出界面
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+			app.Stop()
+		case <-stopped:
+		}
+	}()
 	if err := app.SetRoot(container, true).Run(); err != nil {
 		panic(err)
 	}
+	if ui.job != nil {
+		ui.job.cancel()
+	}
 	return InfoMessage(-1, "程序退出")
 }
 
@@ -219,7 +237,7 @@ func (ui *cliui) historyGo(down bool) {
 	defer func() {
 		if ui.goindex > -1 && ui.goindex < len(ui.histories) {
 			args := ui.histories[ui.goindex]
-			ui.updateInput(fmt.Sprintf("%s ", strings.Join(args, " ")), nil)
+			ui.updateInput(fmt.Sprintf("%s ", JoinArgs(args)), nil)
 		}
 	}()
 	if ui.goindex == -1 {
@@ -266,41 +284,72 @@ func (ui *cliui) exit() {
 }
 
 func (ui *cliui) appendHistory(item string) {
-	args := strings.Fields(item)
-	if len(strings.Fields(item)) == 0 {
+	args, msg := Tokenize(item)
+	if msg != nil {
 		return
 	}
-	items := ui.histories
-	for i := range items {
-		it := items[i]
-		if strings.Join(it, "") == strings.Join(args, "") {
-			return
-		}
-	}
-	hlen := len(items)
-	if hlen >= ui.maxHistory {
-		copy(items, items[1:ui.maxHistory-1])
-		items[ui.maxHistory-1] = args
-	} else {
-		ui.histories = append(items, args)
-	}
+	pushHistory(&ui.histories, ui.maxHistory, args)
 }
 
+// 解析输入后在后台执行指令,同一时间只执行一个指令
 func (ui *cliui) command(input string) {
 	timestr := fomattedNow(DefaultDateFormatter)
-	args, fmap := ParseInputArgs(strings.Fields(input))
-	c, cargs, found := matchCommand(ui.registry, args)
-	if !found {
-		ui.appendConsole(fmt.Sprintf("%s>: %s\n%s", timestr, input, "没有匹配的指令\n"))
+	var (
+		c     RegisteredCommand
+		cargs Args
+		fmap  FlagMap
+	)
+	tokens, perr := Tokenize(input)
+	if perr == nil {
+		c, cargs, fmap, perr = resolveInput(ui.registry, tokens)
+	}
+	if perr != nil {
+		ui.appendConsole(fmt.Sprintf("%s>: %s\n%s\n", timestr, input, FormatMessage(perr)))
+		return
+	}
+	if ui.job != nil {
+		state := "执行中,Ctrl+C取消"
+		if ui.job.cancelling {
+			state = "取消中,请稍候"
+		}
+		ui.appendConsole(fmt.Sprintf("%s>: %s\n%s %s\n", timestr, input, ui.job.input, state))
 		return
 	}
+	ctx, cancel := WithCancel(ui.context)
+	j := &job{input: input, cancel: cancel}
+	ui.job = j
+	ui.updateTitle("")
+	go func() {
+		message := runCommand(ctx, c, cargs, fmap)
+		cancel()
+		ui.window.QueueUpdateDraw(func() {
+			ui.finish(j, timestr, c, message)
+		})
+	}()
+}
+
+// 后台执行的指令
+type job struct {
+	input  string
+	cancel func()
+	// 已取消,等待指令返回
+	cancelling bool
+}
 
-	message := c.Run(&pluginContext{
-		Context: ui.context,
-		ptr:     c.From,
-	}, cargs, fmap)
+// 指令执行结束后释放job,已取消的指令只记录日志
+func (ui *cliui) finish(j *job, timestr string, c RegisteredCommand, message Message) {
+	if ui.job != j {
+		return
+	}
+	ui.job = nil
+	ui.updateTitle("")
+	if j.cancelling {
+		ui.appendConsole(fmt.Sprintf("%s>: %s\n已取消\n", timestr, j.input))
+		ui.logger.Debug("command %s cancelled, result dropped", c.Command.Key())
+		return
+	}
 	if message == nil {
-		ui.appendConsole(fmt.Sprintf("%s>: %s\n%s", timestr, input, "返回空值\n"))
+		ui.appendConsole(fmt.Sprintf("%s>: %s\n%s", timestr, j.input, "返回空值\n"))
 		ui.logger.Debug("command %s run return empty", c.Command.Key())
 		return
 	}
@@ -309,19 +358,25 @@ func (ui *cliui) command(input string) {
 		return
 	}
 
-	err, ok := message.Err()
-	var msg string
-
-	if ok {
-		msg = err.Error()
-	} else {
-		msg = message.Msg()
-	}
-	print := fmt.Sprintf("%s>: %s\n%s", timestr, input, msg)
+	print := fmt.Sprintf("%s>: %s\n%s", timestr, j.input, FormatMessage(message))
 	ui.appendConsole(print)
 	ui.logger.Debug("command %s run result ", c.Command.Key(), print)
 }
 
+// 取消执行中的指令,指令返回前保留job并显示取消中;
+// 没有执行中的指令或已在取消中时返回false
+func (ui *cliui) cancelJob() bool {
+	j := ui.job
+	if j == nil || j.cancelling {
+		return false
+	}
+	j.cancel()
+	j.cancelling = true
+	ui.updateTitle("")
+	ui.appendConsole(fmt.Sprintf("%s>: %s\n取消中...\n", fomattedNow(DefaultDateFormatter), j.input))
+	return true
+}
+
 func (ui *cliui) submit() {
 	txt := strings.TrimSpace(ui.input.GetText())
 	if len(txt) == 0 {
@@ -337,18 +392,59 @@ func (ui *cliui) appendConsole(msg string) {
 	ui.console.Write([]byte(fmt.Sprintf("%s\n", msg)))
 }
 
+// 信息栏标题:执行中的指令及进度
+func (ui *cliui) updateTitle(progress string) {
+	title := consoleTitle
+	if ui.job != nil {
+		state := "运行中"
+		if ui.job.cancelling {
+			state = "取消中"
+		}
+		title = fmt.Sprintf("%s [%s: %s] %s", consoleTitle, state, ui.job.input, progress)
+	}
+	ui.console.SetTitle(title)
+}
+
+// 信息栏的OutputSink,在执行指令的goroutine中调用
+type consoleSink struct {
+	ui    *cliui
+	frame atomic.Int32
+}
+
+func (s *consoleSink) Println(text string, args ...any) {
+	if len(args) > 0 {
+		text = fmt.Sprintf(text, args...)
+	}
+	s.ui.appendConsole(strings.TrimRight(text, "\n"))
+	s.ui.window.Draw()
+}
+
+func (s *consoleSink) Progress(p Progress) {
+	text := p.Text(int(s.frame.Add(1)))
+	s.ui.window.QueueUpdateDraw(func() {
+		if p.Done {
+			s.ui.updateTitle("")
+			s.ui.appendConsole(text)
+		} else {
+			s.ui.updateTitle(text)
+		}
+	})
+}
+
+const consoleTitle = " 信息"
+
 func (ui *cliui) consoleView() *tview.TextView {
 	output := tview.NewTextView()
 	ui.console = output
 	output.SetMaxLines(40)
 	output.SetBorder(true)
-	output.SetTitle(" 信息")
+	output.SetTitle(consoleTitle)
 	return output
 }
 
 func (ui *cliui) helpView() *tview.Table {
 	help := tview.NewTable()
-	tips := []string{"BLANK:空格提示", "ESC:清空输入", "Ctrl+C:退出程序", "Alt+W:清空信息"}
+	tips := []string{"TAB:补全提示", "ESC:清空输入", "Ctrl+C:取消指令/退出程序", "Alt+W:清空信息"}
 	var cell *tview.TableCell
 	for i := range tips {
 		cell = tview.NewTableCell(tips[i])
@@ -377,7 +473,19 @@ func (ui *cliui) shortcutView() *tview.Table {
 
 func (ui *cliui) dipatchInputKeyEvent(e *tcell.EventKey) bool {
 	key := e.Key()
+	// 补全列表显示时,选择及确认按键交给输入框处理
+	if ui.completing {
+		switch key {
+		case tcell.KeyEnter, tcell.KeyEscape:
+			ui.completing = false
+			return true
+		case tcell.KeyUp, tcell.KeyDown, tcell.KeyTab, tcell.KeyBacktab:
+			return true
+		}
+	}
 	switch key {
+	case tcell.KeyTab:
+		ui.input.Autocomplete()
 	case tcell.KeyUp:
 		ui.historyGo(false)
 	case tcell.KeyDown:
@@ -385,7 +493,9 @@ func (ui *cliui) dipatchInputKeyEvent(e *tcell.EventKey) bool {
 	case tcell.KeyEscape:
 		ui.updateInput("", nil)
 	case tcell.KeyCtrlC:
-		ui.exit()
+		if !ui.cancelJob() {
+			ui.exit()
+		}
 	case tcell.KeyEnter:
 		fallthrough
 	case tcell.KeyLF:
@@ -408,52 +518,20 @@ func (ui *cliui) inputView(prompt string) *tview.InputField {
 	input.SetRect(0, 10, 240, 20)
 	input.SetLabel(prompt)
 	input.SetFieldBackgroundColor(tcell.ColorBlack)
+	input.SetAutocompleteFunc(ui.autocomplete)
 	return input
 }
 
-// func autoCompelete(registry Registry) func(currentText string) (entries []string) {
-
-// 	return func(currentText string) (entries []string) {
-// 		commands := make([]string, 0, 7)
-// 		args, flagmap := ParseInputArgs(strings.Fields(currentText))
-// 		if len(args) == 0 {
-// 			registry.RangeRootCommand(func(key string, roots map[string]*RegisteredCommand) (next bool) {
-// 				commands = append(commands, key)
-// 				return true
-// 			})
-// 			return commands
-// 		}
-// 		c, ok := registry.RootCommand(args[0])
-// 		if !ok {
-// 			commands = append(commands, fmt.Sprintf("%s指令无效", args[0]))
-// 			return commands
-// 		}
-// 		emptyFlag := flagmap.Empty()
-// 		subs, ok := c.HasSub()
-// 		if len(args) == 1 && ok && emptyFlag {
-// 			c.Range(func(key string, roots map[string]*RegisteredCommand) (next bool) {
-// 				commands = append(commands, key)
-// 				return true
-// 			})
-// 			return commands
-// 		}
-// 		var flags []Flag
-// 		if c.Command != nil {
-// 			flags = c.Flags()
-// 		}
-// 		if len(args) > 1 {
-// 			sub, ok := subs[args[1]]
-// 			if ok {
-// 				flags = sub.Flags()
-// 			}
-// 		}
-// 		if len(flags) > 0 {
-// 			for i := range flags {
-// 				if _, ok := flagmap.HasFlag(flags[i]); !ok {
-// 					commands = append(commands, flags[i].Name())
-// 				}
-// 			}
-// 		}
-// 		return commands
-// 	}
-// }
+// 输入变化或按TAB时提示补全,与当前输入相同的候选项忽略
+func (ui *cliui) autocomplete(text string) []string {
+	entries := make([]string, 0, 7)
+	if len(strings.TrimSpace(text)) > 0 {
+		for _, line := range completeLine(ui.context, ui.registry, text) {
+			if strings.TrimSpace(line) != strings.TrimSpace(text) {
+				entries = append(entries, line)
+			}
+		}
+	}
+	ui.completing = len(entries) > 0
+	return entries
+}
@@ -0,0 +1,39 @@
+package gocli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+func TestCancelJob(t *testing.T) {
+	log, _ := testContext(t).Logger()
+	ui := &cliui{console: tview.NewTextView(), logger: log, registry: testRegistry(gogenCore)}
+	if ui.cancelJob() {
+		t.Error("no job to cancel")
+	}
+	cancelled := 0
+	j := &job{input: "sleep 10", cancel: func() { cancelled++ }}
+	ui.job = j
+	if !ui.cancelJob() || cancelled != 1 {
+		t.Fatal("job should be cancelled")
+	}
+	// 指令返回前保留job,不能提交新的指令
+	if ui.job != j || !strings.Contains(ui.console.GetTitle(), "[取消中: sleep 10]") {
+		t.Errorf("job should stay until finished, title %q", ui.console.GetTitle())
+	}
+	ui.command("help")
+	if !strings.Contains(ui.console.GetText(false), "sleep 10 取消中,请稍候") {
+		t.Errorf("new command should wait for the cancelling job:\n%s", ui.console.GetText(false))
+	}
+	// 再次Ctrl+C时不再取消,由调用方退出
+	if ui.cancelJob() || cancelled != 1 {
+		t.Error("cancelling job should not be cancelled again")
+	}
+
+	ui.finish(j, "", RegisteredCommand{Command: echoCommand("sleep")}, InfoMessage(0, "done"))
+	if ui.job != nil || strings.Contains(ui.console.GetText(false), "done") || ui.console.GetTitle() != consoleTitle {
+		t.Errorf("cancelled result should be dropped:\n%s", ui.console.GetText(false))
+	}
+}